@@ -0,0 +1,203 @@
+// Package archiver copies log_scores from MySQL to ClickHouse (and
+// optionally the Parquet archive), tracking progress in the
+// log_scores_archive_status table.
+package archiver
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"go.ntppool.org/common/logger"
+	"go.ntppool.org/common/tracing"
+	"go.ntppool.org/data-api/chdb"
+	"go.ntppool.org/data-api/ntpdb"
+)
+
+type Options struct {
+	// Archiver is the name used in log_scores_archive_status
+	Archiver string
+
+	// BatchSize is the maximum number of log scores per batch
+	BatchSize int
+
+	// MinAge skips log scores newer than this so transactions that
+	// are still in flight on the MySQL side don't get missed
+	MinAge time.Duration
+
+	// Export also writes each batch to the archive export target
+	Export bool
+}
+
+type Archiver struct {
+	db   *sql.DB
+	ch   *chdb.ClickHouse
+	opts Options
+}
+
+func New(db *sql.DB, ch *chdb.ClickHouse, opts Options) *Archiver {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 10000
+	}
+	return &Archiver{db: db, ch: ch, opts: opts}
+}
+
+// Run archives batches until there are no more log scores old
+// enough to archive; if interval is set it then waits and starts
+// over until the context is cancelled.
+func (a *Archiver) Run(ctx context.Context, interval time.Duration) error {
+	log := logger.FromContext(ctx)
+
+	for {
+		for {
+			count, err := a.Batch(ctx)
+			if err != nil {
+				return err
+			}
+			if count == 0 {
+				break
+			}
+		}
+
+		if interval == 0 {
+			return nil
+		}
+
+		log.DebugContext(ctx, "waiting for more log scores", "interval", interval)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(interval):
+		}
+	}
+}
+
+// Batch archives one batch of log scores and returns how many were
+// archived.
+//
+// The archive status row is locked for the duration of the batch
+// and only updated after the ClickHouse insert succeeded; it's the
+// only record of how far the archiver got. If the archiver was
+// interrupted between the insert and the commit, the batch is read
+// again and the log scores ClickHouse already has are skipped.
+func (a *Archiver) Batch(ctx context.Context) (int, error) {
+	log := logger.FromContext(ctx)
+	ctx, span := tracing.Tracer().Start(ctx, "archiver.Batch")
+	defer span.End()
+
+	tx, err := a.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	q := ntpdb.NewWrappedQuerier(ntpdb.New(tx))
+
+	status, err := q.GetArchiveStatus(ctx, a.opts.Archiver)
+	if errors.Is(err, sql.ErrNoRows) {
+		log.InfoContext(ctx, "creating archive status", "archiver", a.opts.Archiver)
+		err = q.InsertArchiveStatus(ctx, a.opts.Archiver)
+		if err != nil {
+			return 0, err
+		}
+		status, err = q.GetArchiveStatus(ctx, a.opts.Archiver)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("archive status: %w", err)
+	}
+
+	committedID := uint64(status.LogScoreID.Int64)
+
+	ls, err := q.GetLogScoresAfterID(ctx, ntpdb.GetLogScoresAfterIDParams{
+		ID:    committedID,
+		Limit: int32(a.opts.BatchSize),
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	minTs := time.Now().Add(-a.opts.MinAge)
+	for i, l := range ls {
+		if l.Ts.After(minTs) {
+			ls = ls[:i]
+			break
+		}
+	}
+
+	if len(ls) == 0 {
+		return 0, tx.Commit()
+	}
+
+	firstID, lastID := ls[0].ID, ls[len(ls)-1].ID
+
+	// the ts range of the batch, in whole seconds for the ClickHouse
+	// DateTime column
+	from, to := ls[0].Ts, ls[0].Ts
+	for _, l := range ls {
+		if l.Ts.Before(from) {
+			from = l.Ts
+		}
+		if l.Ts.After(to) {
+			to = l.Ts
+		}
+	}
+	from = from.Truncate(time.Second)
+	to = to.Truncate(time.Second).Add(time.Second)
+
+	existing, err := a.ch.LogScoreIDs(ctx, firstID, lastID, from, to)
+	if err != nil {
+		return 0, fmt.Errorf("clickhouse log score ids: %w", err)
+	}
+
+	insert := make([]ntpdb.LogScore, 0, len(ls))
+	for _, l := range ls {
+		if !existing[l.ID] {
+			insert = append(insert, l)
+		}
+	}
+	if len(existing) > 0 {
+		log.InfoContext(ctx, "log scores already in clickhouse",
+			"archiver", a.opts.Archiver, "count", len(ls)-len(insert),
+			"first_id", firstID, "last_id", lastID)
+	}
+
+	if len(insert) > 0 {
+		err = a.ch.InsertLogScores(ctx, insert)
+		if err != nil {
+			return 0, fmt.Errorf("clickhouse insert: %w", err)
+		}
+	}
+
+	if a.opts.Export {
+		err = a.ch.ExportLogScores(ctx, firstID, lastID, from, to)
+		if err != nil {
+			return 0, fmt.Errorf("archive export: %w", err)
+		}
+	}
+
+	err = q.UpdateArchiveStatus(ctx, ntpdb.UpdateArchiveStatusParams{
+		LogScoreID: sql.NullInt64{Int64: int64(lastID), Valid: true},
+		Archiver:   a.opts.Archiver,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, err
+	}
+
+	span.SetAttributes(
+		attribute.Int("archived", len(ls)),
+		attribute.Int64("log_score_id", int64(lastID)),
+	)
+	log.InfoContext(ctx, "archived log scores", "archiver", a.opts.Archiver, "count", len(ls), "log_score_id", lastID)
+
+	return len(ls), nil
+}
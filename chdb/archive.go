@@ -0,0 +1,194 @@
+package chdb
+
+// queries to the offline log_scores archive (Parquet or Avro files
+// read through a ClickHouse table function)
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"go.ntppool.org/common/logger"
+	"go.ntppool.org/data-api/ntpdb"
+)
+
+// ArchiveEnabled returns true if an archive source and the
+// ClickHouse retention are configured.
+func (d *ClickHouse) ArchiveEnabled() bool {
+	archive := d.conns.Load().archive
+	return archive.Source != "" && archive.Retention > 0
+}
+
+// ArchiveCutoff returns the time before which log scores have to
+// be read from the archive rather than from ClickHouse.
+func (d *ClickHouse) ArchiveCutoff() time.Time {
+	archive := d.conns.Load().archive
+	if archive.Source == "" || archive.Retention == 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(-archive.Retention).Truncate(time.Hour)
+}
+
+// ArchiveLogscores returns the archived log scores matching the query.
+func (d *ClickHouse) ArchiveLogscores(ctx context.Context, q LogscoresQuery) ([]ntpdb.LogScore, error) {
+	log := logger.Setup()
+	ctx, span := startQuery(ctx, "CH ArchiveLogscores")
+	defer span.End()
+
+	conns := d.conns.Load()
+	if conns.archive.Source == "" || conns.archive.Retention == 0 {
+		return nil, fmt.Errorf("archive not configured")
+	}
+
+	// the files might not carry the ClickHouse column types, so
+	// cast the columns that get scanned into fixed width types
+	where, args := q.where()
+	query := `select id,monitor_id,server_id,toDateTime(ts) as ts,
+                toFloat64(score),toFloat64(step),offset,
+                rtt,toUInt8(leap),warning,error
+              from ` + conns.archive.Source + `
+              where ` + where
+
+	orderLimit, args := q.orderLimit(args)
+	query += orderLimit
+
+	log.DebugContext(ctx, "clickhouse archive query", "query", query, "args", args)
+
+	rows, err := conns.scores.Query(
+		queryContext(ctx, span),
+		query, args...,
+	)
+	if err != nil {
+		return nil, queryError(ctx, span, log, err)
+	}
+
+	rv, err := scanLogScores(ctx, rows)
+	if err != nil {
+		return nil, queryError(ctx, span, log, err)
+	}
+
+	return rv, nil
+}
+
+// LogScoreIDs returns the ids from firstID to lastID that are already
+// in the ClickHouse log_scores table. log_scores isn't ordered by id,
+// so the timestamps of the log scores (from up to, but not including,
+// to) limit the query to the parts with the batch.
+func (d *ClickHouse) LogScoreIDs(ctx context.Context, firstID, lastID uint64, from, to time.Time) (map[uint64]bool, error) {
+	log := logger.Setup()
+	ctx, span := startQuery(ctx, "CH LogScoreIDs")
+	defer span.End()
+
+	rows, err := d.Scores().Query(
+		queryContext(ctx, span),
+		`select distinct id from log_scores
+              where ts >= ? and ts < ? and id >= ? and id <= ?`,
+		from, to, firstID, lastID,
+	)
+	if err != nil {
+		return nil, queryError(ctx, span, log, err)
+	}
+	defer rows.Close()
+
+	rv := map[uint64]bool{}
+	for rows.Next() {
+		var id uint64
+		if err := rows.Scan(&id); err != nil {
+			return nil, queryError(ctx, span, log, err)
+		}
+		rv[id] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, queryError(ctx, span, log, err)
+	}
+
+	return rv, nil
+}
+
+// InsertLogScores adds the log scores to the ClickHouse log_scores
+// table in one batch.
+func (d *ClickHouse) InsertLogScores(ctx context.Context, ls []ntpdb.LogScore) error {
+	ctx, span := startQuery(ctx, "CH InsertLogScores")
+	defer span.End()
+
+	batch, err := d.Scores().PrepareBatch(
+		queryContext(ctx, span),
+		"insert into log_scores (id,monitor_id,server_id,ts,score,step,offset,rtt,leap,warning,error)",
+	)
+	if err != nil {
+		return err
+	}
+
+	for _, l := range ls {
+		var offset *float64
+		if l.Offset.Valid {
+			offset = &l.Offset.Float64
+		}
+		var rtt *int32
+		if l.Rtt.Valid {
+			rtt = &l.Rtt.Int32
+		}
+
+		err := batch.Append(
+			l.ID,
+			uint32(l.MonitorID.Int32),
+			l.ServerID,
+			l.Ts,
+			l.Score,
+			l.Step,
+			offset,
+			rtt,
+			uint8(l.Attributes.Leap),
+			l.Attributes.Warning,
+			l.Attributes.Error,
+		)
+		if err != nil {
+			batch.Abort()
+			return fmt.Errorf("log score %d: %w", l.ID, err)
+		}
+	}
+
+	return batch.Send()
+}
+
+// ExportLogScores copies the log scores with ids from firstID to lastID
+// from ClickHouse to the configured archive export target. As with
+// LogScoreIDs, the timestamps of the log scores limit the query.
+func (d *ClickHouse) ExportLogScores(ctx context.Context, firstID, lastID uint64, from, to time.Time) error {
+	log := logger.Setup()
+	ctx, span := startQuery(ctx, "CH ExportLogScores")
+	defer span.End()
+
+	archive := d.conns.Load().archive
+	if archive.Export == "" {
+		return fmt.Errorf("archive export not configured")
+	}
+
+	target := strings.NewReplacer(
+		"{first_id}", strconv.FormatUint(firstID, 10),
+		"{last_id}", strconv.FormatUint(lastID, 10),
+	).Replace(archive.Export)
+
+	query := `insert into function ` + target + `
+              select id,monitor_id,server_id,ts,score,step,offset,
+                rtt,leap,warning,error
+              from log_scores
+              where ts >= ? and ts < ? and id >= ? and id <= ?
+              order by id`
+
+	log.DebugContext(ctx, "clickhouse archive export", "query", query, "first_id", firstID, "last_id", lastID)
+
+	// re-running an export after a crash overwrites the earlier file
+	return d.Scores().Exec(
+		queryContext(ctx, span,
+			clickhouse.WithSettings(clickhouse.Settings{
+				"s3_truncate_on_insert":          1,
+				"engine_file_truncate_on_insert": 1,
+			}),
+		),
+		query, from, to, firstID, lastID,
+	)
+}
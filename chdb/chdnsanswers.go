@@ -2,12 +2,12 @@ package chdb
 
 import (
 	"context"
-	"fmt"
+	"net/netip"
 	"sort"
+	"time"
 
 	"github.com/ClickHouse/clickhouse-go/v2"
 	"go.ntppool.org/common/logger"
-	"go.ntppool.org/common/tracing"
 )
 
 type ccCount struct {
@@ -33,17 +33,15 @@ func (s ServerQueries) Less(i, j int) bool {
 
 func (d *ClickHouse) ServerAnswerCounts(ctx context.Context, serverIP string, days int) (ServerQueries, error) {
 
-	ctx, span := tracing.Tracer().Start(ctx, "ServerAnswerCounts")
+	ctx, span := startQuery(ctx, "ServerAnswerCounts")
 	defer span.End()
 
-	conn := d.Logs
+	conn := d.Logs()
 
 	log := logger.Setup().With("server", serverIP)
 
 	// queries by UserCC / Qtype for the ServerIP
-	rows, err := conn.Query(clickhouse.Context(ctx,
-		clickhouse.WithSpan(span.SpanContext()),
-	), `
+	rows, err := conn.Query(queryContext(ctx, span), `
 	select UserCC,Qtype,sum(queries) as queries
 	from by_server_ip_1d
 	where
@@ -53,8 +51,7 @@ func (d *ClickHouse) ServerAnswerCounts(ctx context.Context, serverIP string, da
 		serverIP, days,
 	)
 	if err != nil {
-		log.Error("query error", "err", err)
-		return nil, fmt.Errorf("database error")
+		return nil, queryError(ctx, span, log, err)
 	}
 
 	rv := ServerQueries{}
@@ -88,6 +85,9 @@ func (d *ClickHouse) ServerAnswerCounts(ctx context.Context, serverIP string, da
 		// slog.Info("set c", "c", c)
 		// slog.Info("totals", "totals", totals)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, queryError(ctx, span, log, err)
+	}
 
 	sort.Sort(rv)
 
@@ -96,13 +96,11 @@ func (d *ClickHouse) ServerAnswerCounts(ctx context.Context, serverIP string, da
 
 func (d *ClickHouse) AnswerTotals(ctx context.Context, qtype string, days int) (ServerTotals, error) {
 	log := logger.Setup()
-	ctx, span := tracing.Tracer().Start(ctx, "AnswerTotals")
+	ctx, span := startQuery(ctx, "AnswerTotals")
 	defer span.End()
 
 	// queries by UserCC / Qtype for the ServerIP
-	rows, err := d.Logs.Query(clickhouse.Context(ctx,
-		clickhouse.WithSpan(span.SpanContext()),
-	), `
+	rows, err := d.Logs().Query(queryContext(ctx, span), `
 	select UserCC,Qtype,sum(queries) as queries
 	from by_server_ip_1d
 	where
@@ -112,8 +110,7 @@ func (d *ClickHouse) AnswerTotals(ctx context.Context, qtype string, days int) (
 		qtype, days,
 	)
 	if err != nil {
-		log.Error("query error", "err", err)
-		return nil, fmt.Errorf("database error")
+		return nil, queryError(ctx, span, log, err)
 	}
 
 	rv := ServerTotals{}
@@ -148,6 +145,245 @@ func (d *ClickHouse) AnswerTotals(ctx context.Context, qtype string, days int) (
 		// slog.Info("set c", "c", c)
 		// slog.Info("totals", "totals", totals)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, queryError(ctx, span, log, err)
+	}
+
+	return rv, nil
+}
+
+// ServerQueriesDay is the DNS answers for a server by UserCC on one
+// day; Date is formatted as YYYY-MM-DD.
+type ServerQueriesDay struct {
+	Date   string
+	Server ServerQueries
+}
+
+// ServerAnswerSeries returns the DNS answers for the server by day and
+// UserCC for the days from 'from' up to and including 'to'. The total
+// for each day has an empty UserCC.
+func (d *ClickHouse) ServerAnswerSeries(ctx context.Context, serverIP string, from, to time.Time) ([]ServerQueriesDay, error) {
+	ctx, span := startQuery(ctx, "ServerAnswerSeries")
+	defer span.End()
+
+	log := logger.Setup().With("server", serverIP)
+
+	rows, err := d.Logs().Query(queryContext(ctx, span), `
+	select toDate(dt) as day,UserCC,sum(queries) as queries
+	from by_server_ip_1d
+	where
+		ServerIP = ? AND dt >= toDate(?) AND dt <= toDate(?)
+		group by grouping sets ((day,UserCC),(day))
+		order by day,UserCC`,
+		serverIP, from, to,
+	)
+	if err != nil {
+		return nil, queryError(ctx, span, log, err)
+	}
+
+	rv := []ServerQueriesDay{}
+
+	for rows.Next() {
+		var (
+			day     time.Time
+			UserCC  string
+			queries uint64
+		)
+		if err := rows.Scan(
+			&day,
+			&UserCC,
+			&queries,
+		); err != nil {
+			log.Error("could not parse row", "err", err)
+			continue
+		}
+
+		date := day.Format(time.DateOnly)
+		if len(rv) == 0 || rv[len(rv)-1].Date != date {
+			rv = append(rv, ServerQueriesDay{Date: date, Server: ServerQueries{}})
+		}
+
+		last := &rv[len(rv)-1]
+		last.Server = append(last.Server, &ccCount{
+			CC:    UserCC,
+			Count: queries,
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, queryError(ctx, span, log, err)
+	}
+
+	for _, d := range rv {
+		sort.Sort(d.Server)
+	}
+
+	return rv, nil
+}
+
+// AnswerTotalsSeries returns the DNS answers of the query type by
+// UserCC for each day (formatted as YYYY-MM-DD) from 'from' up to
+// and including 'to'. The total for each day has an empty UserCC.
+func (d *ClickHouse) AnswerTotalsSeries(ctx context.Context, qtype string, from, to time.Time) (map[string]ServerTotals, error) {
+	log := logger.Setup()
+	ctx, span := startQuery(ctx, "AnswerTotalsSeries")
+	defer span.End()
+
+	rows, err := d.Logs().Query(queryContext(ctx, span), `
+	select toDate(dt) as day,UserCC,sum(queries) as queries
+	from by_server_ip_1d
+	where
+		Qtype = ? AND dt >= toDate(?) AND dt <= toDate(?)
+		group by grouping sets ((day,UserCC),(day))
+		order by day,UserCC`,
+		qtype, from, to,
+	)
+	if err != nil {
+		return nil, queryError(ctx, span, log, err)
+	}
+
+	rv := map[string]ServerTotals{}
+
+	for rows.Next() {
+		var (
+			day     time.Time
+			UserCC  string
+			queries uint64
+		)
+		if err := rows.Scan(
+			&day,
+			&UserCC,
+			&queries,
+		); err != nil {
+			log.Error("could not parse row", "err", err)
+			continue
+		}
+
+		date := day.Format(time.DateOnly)
+		if rv[date] == nil {
+			rv[date] = ServerTotals{}
+		}
+		rv[date][UserCC] = queries
+	}
+	if err := rows.Err(); err != nil {
+		return nil, queryError(ctx, span, log, err)
+	}
+
+	return rv, nil
+}
+
+// ServersAnswerCounts returns the DNS answers by UserCC in the last
+// days for each of the server IPs, like ServerAnswerCounts, from one
+// query. Servers without answers aren't in the returned map.
+func (d *ClickHouse) ServersAnswerCounts(ctx context.Context, serverIPs []string, days int) (map[string]ServerQueries, error) {
+	ctx, span := startQuery(ctx, "ServersAnswerCounts")
+	defer span.End()
+
+	log := logger.Setup().With("servers", serverIPs)
+
+	ips := clickhouse.GroupSet{}
+	for _, ip := range serverIPs {
+		ips.Value = append(ips.Value, ip)
+	}
+
+	rows, err := d.Logs().Query(queryContext(ctx, span), `
+	select toString(ServerIP) as ip,UserCC,sum(queries) as queries
+	from by_server_ip_1d
+	where
+		ServerIP IN ? AND dt > now() - INTERVAL ? DAY
+		group by grouping sets ((ip,UserCC),(ip))
+		order by ip,UserCC`,
+		ips, days,
+	)
+	if err != nil {
+		return nil, queryError(ctx, span, log, err)
+	}
+
+	rv := map[string]ServerQueries{}
+
+	for rows.Next() {
+		var (
+			ip, UserCC string
+			queries    uint64
+		)
+		if err := rows.Scan(
+			&ip,
+			&UserCC,
+			&queries,
+		); err != nil {
+			log.Error("could not parse row", "err", err)
+			continue
+		}
+
+		// IPv4 addresses might be returned as IPv4-mapped IPv6
+		if addr, err := netip.ParseAddr(ip); err == nil {
+			ip = addr.Unmap().String()
+		}
+
+		rv[ip] = append(rv[ip], &ccCount{
+			CC:    UserCC,
+			Count: queries,
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, queryError(ctx, span, log, err)
+	}
+
+	for _, s := range rv {
+		sort.Sort(s)
+	}
+
+	return rv, nil
+}
+
+// AnswerTotalsByQtype returns the DNS answers by UserCC in the last
+// days for each of the query types, like AnswerTotals, from one query.
+func (d *ClickHouse) AnswerTotalsByQtype(ctx context.Context, qtypes []string, days int) (map[string]ServerTotals, error) {
+	log := logger.Setup()
+	ctx, span := startQuery(ctx, "AnswerTotalsByQtype")
+	defer span.End()
+
+	qt := clickhouse.GroupSet{}
+	for _, qtype := range qtypes {
+		qt.Value = append(qt.Value, qtype)
+	}
+
+	rows, err := d.Logs().Query(queryContext(ctx, span), `
+	select Qtype,UserCC,sum(queries) as queries
+	from by_server_ip_1d
+	where
+		Qtype IN ? AND dt > now() - INTERVAL ? DAY
+		group by grouping sets ((Qtype,UserCC),(Qtype))
+		order by Qtype,UserCC`,
+		qt, days,
+	)
+	if err != nil {
+		return nil, queryError(ctx, span, log, err)
+	}
+
+	rv := map[string]ServerTotals{}
+
+	for rows.Next() {
+		var (
+			Qtype, UserCC string
+			queries       uint64
+		)
+		if err := rows.Scan(
+			&Qtype,
+			&UserCC,
+			&queries,
+		); err != nil {
+			log.Error("could not parse row", "err", err)
+			continue
+		}
+
+		if rv[Qtype] == nil {
+			rv[Qtype] = ServerTotals{}
+		}
+		rv[Qtype][UserCC] = queries
+	}
+	if err := rows.Err(); err != nil {
+		return nil, queryError(ctx, span, log, err)
+	}
 
 	return rv, nil
 }
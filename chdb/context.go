@@ -0,0 +1,59 @@
+package chdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type quotaKeyCtxKey struct{}
+
+// WithQuotaKey returns a context that sets the ClickHouse quota_key
+// on the queries made with it, so quotas keyed by client key can
+// limit each API client (by IP) separately.
+func WithQuotaKey(ctx context.Context, quotaKey string) context.Context {
+	return context.WithValue(ctx, quotaKeyCtxKey{}, quotaKey)
+}
+
+// queryContext returns the context for a ClickHouse query, with the
+// span, the quota key if one is set on ctx and the callbacks for the
+// query progress and profile info.
+func queryContext(ctx context.Context, span *querySpan, options ...clickhouse.QueryOption) context.Context {
+	options = append(options,
+		clickhouse.WithSpan(span.SpanContext()),
+		clickhouse.WithProgress(span.progress),
+		clickhouse.WithProfileInfo(span.profileInfo),
+	)
+	if quotaKey, ok := ctx.Value(quotaKeyCtxKey{}).(string); ok && len(quotaKey) > 0 {
+		options = append(options, clickhouse.WithQuotaKey(quotaKey))
+	}
+	return clickhouse.Context(ctx, options...)
+}
+
+// queryError logs a query error and returns the error for the
+// caller. If the context was cancelled (the client went away or the
+// request timeout passed) the query was cancelled on the server and
+// the context error is returned.
+func queryError(ctx context.Context, span trace.Span, log *slog.Logger, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		log.WarnContext(ctx, "query cancelled", "err", err, "cause", context.Cause(ctx))
+		span.AddEvent("query cancelled")
+		span.SetStatus(codes.Error, ctxErr.Error())
+		return fmt.Errorf("database query cancelled: %w", ctxErr)
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		log.WarnContext(ctx, "query cancelled", "err", err)
+		span.AddEvent("query cancelled")
+		span.SetStatus(codes.Error, err.Error())
+		return fmt.Errorf("database query cancelled: %w", err)
+	}
+	log.ErrorContext(ctx, "query error", "err", err)
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+	return fmt.Errorf("database error")
+}
@@ -2,8 +2,15 @@ package chdb
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"math"
+	"net"
 	"os"
+	"regexp"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"dario.cat/mergo"
@@ -12,39 +19,185 @@ import (
 
 	"go.ntppool.org/common/logger"
 	"go.ntppool.org/common/version"
+
+	"go.ntppool.org/data-api/envconfig"
 )
 
 type Config struct {
 	ClickHouse struct {
-		Scores DBConfig `yaml:"scores"`
-		Logs   DBConfig `yaml:"logs"`
+		Scores  DBConfig      `yaml:"scores"`
+		Logs    DBConfig      `yaml:"logs"`
+		Archive ArchiveConfig `yaml:"archive"`
+		NTP     NTPConfig     `yaml:"ntp"`
 	} `yaml:"clickhouse"`
 }
 
 type DBConfig struct {
-	DSN string
+	DSN string `flag:"dsn" usage:"ClickHouse DSN"`
+
+	// Host is one or more (comma separated) host names or IP
+	// addresses, optionally with a port; Hosts is a list of them.
+	// IPv6 addresses without a port can be written with or
+	// without brackets. The default port is 9000, or 9440 with TLS.
+	Host     string   `flag:"host" usage:"ClickHouse host(s)"`
+	Hosts    []string `yaml:"hosts"`
+	Database string   `flag:"database" usage:"ClickHouse database"`
+
+	User     string `flag:"user"`
+	Password string `flag:"pass"`
+
+	// MaxExecutionTime and MaxMemoryUsage (in bytes) are the
+	// ClickHouse settings for the queries; ReadOnly sets readonly=2
+	// so the connection can't write but the queries can still
+	// change settings.
+	MaxExecutionTime time.Duration `yaml:"max_execution_time"`
+	MaxMemoryUsage   uint64        `yaml:"max_memory_usage"`
+	ReadOnly         bool          `yaml:"read_only"`
+
+	MaxOpenConns    int           `yaml:"max_open_conns"`
+	MaxIdleConns    int           `yaml:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime"`
+
+	// Compression is "lz4" (the default), "zstd" or "none"
+	Compression string `yaml:"compression"`
+
+	// ConnOpenStrategy is how the host for a new connection is
+	// picked: "in_order" (the default), "round_robin" or "random"
+	ConnOpenStrategy string `yaml:"conn_open_strategy"`
+
+	TLS                   bool `yaml:"tls"`
+	TLSInsecureSkipVerify bool `yaml:"tls_insecure_skip_verify"`
+
+	// Settings are other ClickHouse settings for the queries, for
+	// example for querying Distributed tables: load_balancing,
+	// prefer_localhost_replica, skip_unavailable_shards or
+	// max_parallel_replicas.
+	Settings map[string]any `yaml:"settings"`
+}
+
+// applyEnv overrides the configuration with the environment variables
+// with the prefix (after DATA_API_), for example DATA_API_CH_SCORES_DSN,
+// _HOST, _DATABASE, _USER and _PASSWORD (or their _FILE variants).
+func (cfg *DBConfig) applyEnv(prefix string) error {
+	return envconfig.Override(map[string]*string{
+		prefix + "DSN":      &cfg.DSN,
+		prefix + "HOST":     &cfg.Host,
+		prefix + "DATABASE": &cfg.Database,
+		prefix + "USER":     &cfg.User,
+		prefix + "PASSWORD": &cfg.Password,
+	})
+}
+
+// configFlags are the flag prefixes and the environment variable
+// prefixes (after DATA_API_) for the ClickHouse connections
+var configFlags = []struct {
+	flag, env string
+	dbConfig  func(*Config) *DBConfig
+}{
+	{"clickhouse.scores.", "CH_SCORES_", func(cfg *Config) *DBConfig { return &cfg.ClickHouse.Scores }},
+	{"clickhouse.logs.", "CH_LOGS_", func(cfg *Config) *DBConfig { return &cfg.ClickHouse.Logs }},
+	{"clickhouse.ntp.", "CH_NTP_", func(cfg *Config) *DBConfig { return &cfg.ClickHouse.NTP.DBConfig }},
+}
 
-	Host     string
-	Database string
+// ConfigFlags are the command line flags for the ClickHouse
+// connection settings, for example --clickhouse.scores.pass; they
+// override the configuration file and the environment.
+func ConfigFlags() []envconfig.Flag {
+	flags := []envconfig.Flag{}
+	for _, f := range configFlags {
+		flags = append(flags, envconfig.Flags(f.flag, &DBConfig{})...)
+	}
+	return flags
+}
+
+// ArchiveConfig configures the offline archive of log_scores that
+// are older than the ClickHouse retention. Source is a ClickHouse
+// table function used to read the archive files, for example
+// "s3('https://bucket.example/log_scores/*.parquet', 'Parquet')"
+// or "file('log_scores/*.avro', 'Avro')". Retention is how far back
+// the log_scores table in ClickHouse goes.
+//
+// Export is the table function the archiver writes batches to; the
+// {first_id} and {last_id} placeholders are replaced with the range
+// of log_scores ids in the batch.
+type ArchiveConfig struct {
+	Source    string        `yaml:"source"`
+	Retention time.Duration `yaml:"retention"`
+	Export    string        `yaml:"export"`
+}
 
-	User     string
-	Password string
+// NTPConfig configures the optional NTP packet counts. Table has
+// the packets by time and server IP (see testdata/ntp_packets.sql);
+// it's read with the Logs connection unless a DSN or host is set.
+type NTPConfig struct {
+	Enabled  bool   `yaml:"enabled"`
+	Table    string `yaml:"table"`
+	DBConfig `yaml:",inline"`
 }
 
+// ClickHouse has the connections to the ClickHouse databases. They
+// are replaced when the configuration file is reloaded (see Watch).
 type ClickHouse struct {
-	Logs   clickhouse.Conn
-	Scores clickhouse.Conn
+	configFile string
+	conns      atomic.Pointer[connections]
 }
 
+// connections are the ClickHouse connections and settings from one
+// read of the configuration file
+type connections struct {
+	logs   clickhouse.Conn
+	scores clickhouse.Conn
+
+	// ntp is the connection for the NTP packet counts; it's nil
+	// if they aren't enabled
+	ntp clickhouse.Conn
+
+	// hosts are single host connections to each of the configured
+	// hosts, for checking them separately
+	hosts []hostConn
+
+	archive   ArchiveConfig
+	ntpConfig NTPConfig
+}
+
+// hostConn is a connection to one of the hosts of a database
+type hostConn struct {
+	database string
+	addr     string
+	conn     clickhouse.Conn
+}
+
+// tableNameRe matches the table names that can be configured, with
+// an optional database name
+var tableNameRe = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_]*\.)?[A-Za-z_][A-Za-z0-9_]*$`)
+
 func New(ctx context.Context, dbConfigPath string) (*ClickHouse, error) {
-	ch, err := setupClickhouse(ctx, dbConfigPath)
+	conns, err := setupClickhouse(ctx, dbConfigPath)
 	if err != nil {
 		return nil, err
 	}
+	ch := &ClickHouse{configFile: dbConfigPath}
+	ch.conns.Store(conns)
 	return ch, nil
 }
 
-func setupClickhouse(ctx context.Context, configFile string) (*ClickHouse, error) {
+// Logs is the connection to the GeoDNS logs database.
+func (d *ClickHouse) Logs() clickhouse.Conn {
+	return d.conns.Load().logs
+}
+
+// Scores is the connection to the log_scores database.
+func (d *ClickHouse) Scores() clickhouse.Conn {
+	return d.conns.Load().scores
+}
+
+// NTP is the connection for the NTP packet counts; it's nil if they
+// aren't enabled.
+func (d *ClickHouse) NTP() clickhouse.Conn {
+	return d.conns.Load().ntp
+}
+
+func setupClickhouse(ctx context.Context, configFile string) (*connections, error) {
 	log := logger.FromContext(ctx)
 
 	log.DebugContext(ctx, "opening ch config", "file", configFile)
@@ -53,6 +206,7 @@ func setupClickhouse(ctx context.Context, configFile string) (*ClickHouse, error
 	if err != nil {
 		return nil, err
 	}
+	defer dbFile.Close()
 
 	dec := yaml.NewDecoder(dbFile)
 
@@ -63,23 +217,101 @@ func setupClickhouse(ctx context.Context, configFile string) (*ClickHouse, error
 		return nil, err
 	}
 
-	ch := &ClickHouse{}
+	for _, f := range configFlags {
+		dbCfg := f.dbConfig(&cfg)
+		err = dbCfg.applyEnv(f.env)
+		if err != nil {
+			return nil, err
+		}
+		envconfig.ApplyFlags(f.flag, dbCfg)
+	}
+
+	ntpCfg := cfg.ClickHouse.NTP
+	if ntpCfg.Enabled && !tableNameRe.MatchString(ntpCfg.Table) {
+		return nil, fmt.Errorf("invalid clickhouse ntp table name %q", ntpCfg.Table)
+	}
+
+	ch := &connections{
+		archive: cfg.ClickHouse.Archive,
+	}
 
-	ch.Logs, err = open(ctx, cfg.ClickHouse.Logs)
+	ch.logs, err = ch.open(ctx, "logs", cfg.ClickHouse.Logs)
 	if err != nil {
 		return nil, err
 	}
-	ch.Scores, err = open(ctx, cfg.ClickHouse.Scores)
+	ch.scores, err = ch.open(ctx, "scores", cfg.ClickHouse.Scores)
 	if err != nil {
+		ch.close()
 		return nil, err
 	}
 
+	if ntpCfg.Enabled {
+		ch.ntpConfig = ntpCfg
+
+		if ntpCfg.DSN != "" || ntpCfg.Host != "" {
+			ch.ntp, err = ch.open(ctx, "ntp", ntpCfg.DBConfig)
+			if err != nil {
+				ch.close()
+				return nil, err
+			}
+		} else {
+			ch.ntp = ch.logs
+		}
+	}
+
 	return ch, nil
 }
 
-func open(ctx context.Context, cfg DBConfig) (clickhouse.Conn, error) {
-	log := logger.Setup()
+// close closes the connections
+func (ch *connections) close() error {
+	errs := []error{}
+	conns := []clickhouse.Conn{ch.logs, ch.scores, ch.ntp}
+	for _, h := range ch.hosts {
+		conns = append(conns, h.conn)
+	}
+	for _, conn := range conns {
+		if conn == nil || (conn == ch.ntp && conn == ch.logs) {
+			continue
+		}
+		if err := conn.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
 
+// open opens the connection to the database and the connections
+// to each of its hosts.
+func (ch *connections) open(ctx context.Context, database string, cfg DBConfig) (clickhouse.Conn, error) {
+	options, err := clickhouseOptions(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("clickhouse %s: %w", database, err)
+	}
+
+	conn, err := openConn(ctx, options)
+	if err != nil {
+		return nil, fmt.Errorf("clickhouse %s: %w", database, err)
+	}
+
+	for _, addr := range options.Addr {
+		hostOptions := *options
+		hostOptions.Addr = []string{addr}
+		hostOptions.MaxOpenConns = 1
+		hostOptions.MaxIdleConns = 1
+
+		// the connection is made when the host is checked
+		hc, err := clickhouse.Open(&hostOptions)
+		if err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("clickhouse %s %s: %w", database, addr, err)
+		}
+		ch.hosts = append(ch.hosts, hostConn{database: database, addr: addr, conn: hc})
+	}
+
+	return conn, nil
+}
+
+func clickhouseOptions(cfg DBConfig) (*clickhouse.Options, error) {
 	options := &clickhouse.Options{
 		Protocol: clickhouse.Native,
 		Settings: clickhouse.Settings{
@@ -123,15 +355,39 @@ func open(ctx context.Context, cfg DBConfig) (clickhouse.Conn, error) {
 		}
 	}
 
+	if cfg.TLS && options.TLS == nil {
+		options.TLS = &tls.Config{
+			InsecureSkipVerify: cfg.TLSInsecureSkipVerify,
+		}
+	}
+
+	hosts := []string{}
 	if cfg.Host != "" {
-		options.Addr = []string{cfg.Host}
+		hosts = append(hosts, strings.Split(cfg.Host, ",")...)
+	}
+	hosts = append(hosts, cfg.Hosts...)
+	if len(hosts) > 0 {
+		options.Addr = hosts
 	}
 
-	if len(options.Addr) > 0 {
-		// todo: support literal ipv6; or just require port to be configured explicitly
-		if !strings.Contains(options.Addr[0], ":") {
-			options.Addr[0] += ":9000"
-		}
+	defaultPort := "9000"
+	if options.TLS != nil {
+		defaultPort = "9440"
+	}
+	for i, addr := range options.Addr {
+		options.Addr[i] = hostPort(strings.TrimSpace(addr), defaultPort)
+	}
+
+	switch cfg.ConnOpenStrategy {
+	case "":
+	case "in_order":
+		options.ConnOpenStrategy = clickhouse.ConnOpenInOrder
+	case "round_robin":
+		options.ConnOpenStrategy = clickhouse.ConnOpenRoundRobin
+	case "random":
+		options.ConnOpenStrategy = clickhouse.ConnOpenRandom
+	default:
+		return nil, fmt.Errorf("unknown conn_open_strategy %q", cfg.ConnOpenStrategy)
 	}
 
 	if cfg.Database != "" {
@@ -146,6 +402,28 @@ func open(ctx context.Context, cfg DBConfig) (clickhouse.Conn, error) {
 		options.Auth.Password = cfg.Password
 	}
 
+	err := applySettings(options, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return options, nil
+}
+
+// hostPort adds the default port to the address if it doesn't have
+// one. Addresses that are IPv6 literals without a port are put in
+// brackets.
+func hostPort(addr, defaultPort string) string {
+	if _, _, err := net.SplitHostPort(addr); err == nil {
+		return addr
+	}
+	host := strings.TrimSuffix(strings.TrimPrefix(addr, "["), "]")
+	return net.JoinHostPort(host, defaultPort)
+}
+
+func openConn(ctx context.Context, options *clickhouse.Options) (clickhouse.Conn, error) {
+	log := logger.Setup()
+
 	conn, err := clickhouse.Open(options)
 	if err != nil {
 		return nil, err
@@ -165,3 +443,56 @@ func open(ctx context.Context, cfg DBConfig) (clickhouse.Conn, error) {
 
 	return conn, nil
 }
+
+// applySettings sets the query settings, pool sizes and compression
+// from the configuration on the connection options.
+func applySettings(options *clickhouse.Options, cfg DBConfig) error {
+	if options.Settings == nil {
+		options.Settings = clickhouse.Settings{}
+	}
+
+	if cfg.MaxExecutionTime > 0 {
+		// the setting is in whole seconds and 0 is unlimited, so
+		// round up
+		options.Settings["max_execution_time"] = int(math.Ceil(cfg.MaxExecutionTime.Seconds()))
+	}
+	if cfg.MaxMemoryUsage > 0 {
+		options.Settings["max_memory_usage"] = cfg.MaxMemoryUsage
+	}
+	if cfg.ReadOnly {
+		options.Settings["readonly"] = 2
+	}
+
+	if cfg.MaxOpenConns > 0 {
+		options.MaxOpenConns = cfg.MaxOpenConns
+	}
+	if cfg.MaxIdleConns > 0 {
+		options.MaxIdleConns = cfg.MaxIdleConns
+	}
+	if cfg.ConnMaxLifetime > 0 {
+		options.ConnMaxLifetime = cfg.ConnMaxLifetime
+	}
+
+	for k, v := range cfg.Settings {
+		if b, ok := v.(bool); ok {
+			// ClickHouse wants 0 or 1
+			v = 0
+			if b {
+				v = 1
+			}
+		}
+		options.Settings[k] = v
+	}
+
+	switch cfg.Compression {
+	case "", "lz4":
+	case "zstd":
+		options.Compression = &clickhouse.Compression{Method: clickhouse.CompressionZSTD}
+	case "none":
+		options.Compression = &clickhouse.Compression{Method: clickhouse.CompressionNone}
+	default:
+		return fmt.Errorf("unknown clickhouse compression %q", cfg.Compression)
+	}
+
+	return nil
+}
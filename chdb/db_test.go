@@ -0,0 +1,28 @@
+package chdb
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+)
+
+func TestApplySettingsMaxExecutionTime(t *testing.T) {
+	for _, tt := range []struct {
+		d    time.Duration
+		want int
+	}{
+		{500 * time.Millisecond, 1},
+		{time.Second, 1},
+		{1500 * time.Millisecond, 2},
+		{time.Minute, 60},
+	} {
+		options := &clickhouse.Options{}
+		if err := applySettings(options, DBConfig{MaxExecutionTime: tt.d}); err != nil {
+			t.Fatal(err)
+		}
+		if got := options.Settings["max_execution_time"]; got != tt.want {
+			t.Errorf("%s: max_execution_time %v, expected %d", tt.d, got, tt.want)
+		}
+	}
+}
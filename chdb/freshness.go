@@ -0,0 +1,60 @@
+package chdb
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"go.ntppool.org/common/logger"
+)
+
+// DataTable is a table checked for how recent its data is
+type DataTable string
+
+const (
+	TableLogScores     DataTable = "log_scores"
+	TableServerIP1d    DataTable = "by_server_ip_1d"
+	TableUserCountry1d DataTable = "by_usercc_1d"
+)
+
+// DataTables are the tables NewestTime can check
+var DataTables = []DataTable{TableLogScores, TableServerIP1d, TableUserCountry1d}
+
+// NewestTime returns the time of the newest row in the table; it's
+// the zero time if the table is empty.
+func (d *ClickHouse) NewestTime(ctx context.Context, table DataTable) (time.Time, error) {
+	log := logger.Setup().With("table", table)
+	ctx, span := startQuery(ctx, "NewestTime")
+	defer span.End()
+
+	var conn clickhouse.Conn
+	var query string
+
+	switch table {
+	case TableLogScores:
+		conn = d.Scores()
+		query = "select max(ts) from log_scores"
+	case TableServerIP1d:
+		conn = d.Logs()
+		query = "select toDateTime(max(dt)) from by_server_ip_1d"
+	case TableUserCountry1d:
+		conn = d.Logs()
+		query = "select toDateTime(max(dt)) from by_usercc_1d"
+	default:
+		return time.Time{}, fmt.Errorf("unknown table %q", table)
+	}
+
+	var newest time.Time
+	err := conn.QueryRow(queryContext(ctx, span), query).Scan(&newest)
+	if err != nil {
+		return time.Time{}, queryError(ctx, span, log, err)
+	}
+
+	// max() of an empty table is the epoch
+	if newest.Unix() <= 0 {
+		return time.Time{}, nil
+	}
+
+	return newest, nil
+}
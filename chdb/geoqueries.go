@@ -4,13 +4,13 @@ package chdb
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sort"
 	"time"
 
 	"github.com/ClickHouse/clickhouse-go/v2"
 	"go.ntppool.org/common/logger"
-	"go.ntppool.org/common/tracing"
 )
 
 type flatAPI struct {
@@ -35,14 +35,13 @@ func (s UserCountry) Less(i, j int) bool {
 
 func (d *ClickHouse) UserCountryData(ctx context.Context) (*UserCountry, error) {
 	log := logger.Setup()
-	ctx, span := tracing.Tracer().Start(ctx, "UserCountryData")
+	ctx, span := startQuery(ctx, "UserCountryData")
 	defer span.End()
 
-	rows, err := d.Logs.Query(clickhouse.Context(ctx, clickhouse.WithSpan(span.SpanContext())),
+	rows, err := d.Logs().Query(queryContext(ctx, span),
 		"select max(dt) as d,UserCC,Qtype,sum(queries) as queries from by_usercc_1d where dt > now() - INTERVAL 4 DAY group by rollup(Qtype,UserCC) order by UserCC,Qtype;")
 	if err != nil {
-		log.ErrorContext(ctx, "query error", "err", err)
-		return nil, fmt.Errorf("database error")
+		return nil, queryError(ctx, span, log, err)
 	}
 
 	type counts struct {
@@ -134,6 +133,9 @@ func (d *ClickHouse) UserCountryData(ctx context.Context) (*UserCountry, error)
 		// slog.Info("set c", "c", c)
 		// slog.Info("totals", "totals", totals)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, queryError(ctx, span, log, err)
+	}
 
 	// spew.Dump(totals)
 
@@ -149,7 +151,7 @@ func (d *ClickHouse) UserCountryData(ctx context.Context) (*UserCountry, error)
 			continue
 		}
 		total4 := float64(totalDay.Count4)
-		total6 := float64(totalDay.Count4)
+		total6 := float64(totalDay.Count6)
 
 		for _, cc := range cdata.CC {
 			cc.PercentTotal.V4 = (100 / total4) * float64(cc.Count4)
@@ -186,54 +188,289 @@ func (d *ClickHouse) UserCountryData(ctx context.Context) (*UserCountry, error)
 	return nil, nil
 }
 
+// UserCountryCount is the A and AAAA queries from a country and the
+// percentage of all the queries of each type.
+type UserCountryCount struct {
+	CC     string
+	Count4 uint64
+	Count6 uint64
+	V4     float64
+	V6     float64
+}
+
+// UserCountryDay is the queries by country on one day; Date is
+// formatted as YYYY-MM-DD.
+type UserCountryDay struct {
+	Date   string
+	Count4 uint64
+	Count6 uint64
+	UserCC []UserCountryCount
+}
+
+// UserCountrySeries returns the A and AAAA queries by country for
+// each day from 'from' up to and including 'to'.
+func (d *ClickHouse) UserCountrySeries(ctx context.Context, from, to time.Time) ([]UserCountryDay, error) {
+	log := logger.Setup()
+	ctx, span := startQuery(ctx, "UserCountrySeries")
+	defer span.End()
+
+	rows, err := d.Logs().Query(queryContext(ctx, span),
+		`
+	select toDate(dt) as day,UserCC,Qtype,sum(queries) as queries
+	from by_usercc_1d
+	where
+		dt >= toDate(?) AND dt <= toDate(?) AND Qtype IN ('A', 'AAAA')
+		group by grouping sets ((day,UserCC,Qtype),(day,Qtype))
+		order by day,UserCC,Qtype`,
+		from, to,
+	)
+	if err != nil {
+		return nil, queryError(ctx, span, log, err)
+	}
+
+	rv := []UserCountryDay{}
+	// index in UserCC by country for the current day
+	ccs := map[string]int{}
+
+	for rows.Next() {
+		var (
+			day           time.Time
+			UserCC, Qtype string
+			queries       uint64
+		)
+		if err := rows.Scan(&day, &UserCC, &Qtype, &queries); err != nil {
+			log.ErrorContext(ctx, "could not parse row", "err", err)
+			continue
+		}
+
+		date := day.Format(time.DateOnly)
+		if len(rv) == 0 || rv[len(rv)-1].Date != date {
+			rv = append(rv, UserCountryDay{Date: date})
+			ccs = map[string]int{}
+		}
+		last := &rv[len(rv)-1]
+
+		if len(UserCC) == 0 {
+			// total for the day
+			switch Qtype {
+			case "A":
+				last.Count4 = queries
+			case "AAAA":
+				last.Count6 = queries
+			}
+			continue
+		}
+
+		idx, ok := ccs[UserCC]
+		if !ok {
+			idx = len(last.UserCC)
+			last.UserCC = append(last.UserCC, UserCountryCount{CC: UserCC})
+			ccs[UserCC] = idx
+		}
+		c := &last.UserCC[idx]
+
+		switch Qtype {
+		case "A":
+			c.Count4 = queries
+		case "AAAA":
+			c.Count6 = queries
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, queryError(ctx, span, log, err)
+	}
+
+	for i := range rv {
+		day := &rv[i]
+		for j := range day.UserCC {
+			cc := &day.UserCC[j]
+			if day.Count4 > 0 {
+				cc.V4 = (100 / float64(day.Count4)) * float64(cc.Count4)
+			}
+			if day.Count6 > 0 {
+				cc.V6 = (100 / float64(day.Count6)) * float64(cc.Count6)
+			}
+		}
+		sort.Slice(day.UserCC, func(i, j int) bool {
+			return day.UserCC[i].Count4 > day.UserCC[j].Count4
+		})
+	}
+
+	return rv, nil
+}
+
 type DNSQueryCounts struct {
 	T   uint32  `json:"t"`
 	Avg float64 `json:"avg"`
 	Max uint64  `json:"max"`
 }
 
-func (d *ClickHouse) DNSQueries(ctx context.Context) ([]DNSQueryCounts, error) {
+// DNSQueryRange is the time range, bucket size and origins for
+// DNSQueries
+type DNSQueryRange struct {
+	From    time.Time
+	To      time.Time
+	Step    time.Duration
+	Origins []string
+}
+
+// DNSQueries returns the average and max queries per second for the
+// origins in buckets of r.Step from r.From up to r.To.
+func (d *ClickHouse) DNSQueries(ctx context.Context, r DNSQueryRange) ([]DNSQueryCounts, error) {
 	log := logger.Setup()
-	ctx, span := tracing.Tracer().Start(ctx, "DNSQueries")
+	ctx, span := startQuery(ctx, "DNSQueries")
 	defer span.End()
 
-	startUnix := time.Now().Add(2 * time.Hour * -1).Unix()
-	startUnix -= startUnix % (60 * 5)
+	step := int64(r.Step.Seconds())
+	if step < 1 {
+		return nil, fmt.Errorf("invalid step %s", r.Step)
+	}
+
+	startUnix := r.From.Unix()
+	startUnix -= startUnix % step
 
-	log.InfoContext(ctx, "start time", "start", startUnix)
+	log.InfoContext(ctx, "start time", "start", startUnix, "end", r.To.Unix(), "step", step)
 
-	rows, err := d.Logs.Query(clickhouse.Context(ctx, clickhouse.WithSpan(span.SpanContext())),
+	origins := clickhouse.GroupSet{}
+	for _, o := range r.Origins {
+		origins.Value = append(origins.Value, o)
+	}
+
+	rows, err := d.Logs().Query(queryContext(ctx, span),
 		`
-	select toUnixTimestamp(toStartOfFiveMinute(t)) as t,
-  sum(q)/300 as avg, max(q) as max
+	select toUnixTimestamp(toStartOfInterval(t, INTERVAL ? SECOND)) as t,
+  sum(q)/? as avg, max(q) as max
 from (
  select window as t, sumSimpleState(queries) as q
  from geodns.by_origin_1s
  where
-   window > FROM_UNIXTIME(?)
-   and Origin IN ('pool.ntp.org', 'g.ntpns.org')
+   window >= FROM_UNIXTIME(?)
+   and window < FROM_UNIXTIME(?)
+   and Origin IN ?
  group by t order by t
 )
 group by t order by t
-`, startUnix)
+`, step, step, startUnix, r.To.Unix(), origins)
 	if err != nil {
-		log.ErrorContext(ctx, "query error", "err", err)
-		return nil, fmt.Errorf("database error")
+		return nil, queryError(ctx, span, log, err)
 	}
 
 	var t uint32
 	var avg float64
 	var max uint64
 
-	r := []DNSQueryCounts{}
+	rv := []DNSQueryCounts{}
 
 	for rows.Next() {
 		if err := rows.Scan(&t, &avg, &max); err != nil {
 			return nil, err
 		}
-		log.InfoContext(ctx, "data", "t", t, "avg", avg, "max", max)
-		r = append(r, DNSQueryCounts{t, avg, max})
+		rv = append(rv, DNSQueryCounts{t, avg, max})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, queryError(ctx, span, log, err)
+	}
+
+	return rv, nil
+}
+
+// VendorZoneDay is the queries for a vendor zone on one day, in total
+// and by query type and user country.
+type VendorZoneDay struct {
+	Date    string
+	Queries uint64
+	Qtype   map[string]uint64
+	UserCC  map[string]uint64
+}
+
+// vendorZoneRawDays is how many days of queries VendorZoneQueries
+// reads from the raw queries table if the by_origin_label_1d rollup
+// doesn't exist.
+const vendorZoneRawDays = 7
+
+// unknownTableCode is the ClickHouse UNKNOWN_TABLE error code
+const unknownTableCode = 60
+
+// VendorZoneQueries returns the queries by day for the vendor zone
+// label (and the numbered labels below it, like "0.label") in the
+// origin, for the days from 'from' up to and including 'to'. The
+// counts are from the by_origin_label_1d rollup (see
+// schema/by_origin_label_1d.sql); without it only the last
+// vendorZoneRawDays days of the range are counted from the queries
+// table.
+func (d *ClickHouse) VendorZoneQueries(ctx context.Context, origin, label string, from, to time.Time) ([]VendorZoneDay, error) {
+	log := logger.Setup().With("origin", origin, "label", label)
+	ctx, span := startQuery(ctx, "VendorZoneQueries")
+	defer span.End()
+
+	rows, err := d.Logs().Query(queryContext(ctx, span),
+		`
+	select dt as day, Qtype, UserCC, sum(queries) as queries
+	from by_origin_label_1d
+	where
+		Origin = ?
+		AND (LabelName = ? OR endsWith(LabelName, ?))
+		AND dt >= toDate(?) AND dt <= toDate(?)
+	group by day, Qtype, UserCC
+	order by day
+`, origin, label, "."+label, from, to)
+
+	var exception *clickhouse.Exception
+	if errors.As(err, &exception) && exception.Code == unknownTableCode {
+		rawFrom := to.AddDate(0, 0, -(vendorZoneRawDays - 1))
+		if from.Before(rawFrom) {
+			from = rawFrom
+		}
+		log.WarnContext(ctx, "by_origin_label_1d table missing, counting the raw queries",
+			"from", from.Format(time.DateOnly))
+		span.AddEvent("raw queries fallback")
+
+		rows, err = d.Logs().Query(queryContext(ctx, span),
+			`
+	select toDate(Time) as day, Qtype, UserCC, count(*) as queries
+	from queries
+	where
+		Origin = ?
+		AND (LabelName = ? OR endsWith(LabelName, ?))
+		AND Time >= toDate(?) AND Time < toDate(?) + INTERVAL 1 DAY
+	group by day, Qtype, UserCC
+	order by day
+`, origin, label, "."+label, from, to)
+	}
+	if err != nil {
+		return nil, queryError(ctx, span, log, err)
+	}
+
+	rv := []VendorZoneDay{}
+
+	for rows.Next() {
+		var (
+			day           time.Time
+			Qtype, UserCC string
+			queries       uint64
+		)
+		if err := rows.Scan(&day, &Qtype, &UserCC, &queries); err != nil {
+			log.ErrorContext(ctx, "could not parse row", "err", err)
+			continue
+		}
+
+		date := day.Format(time.DateOnly)
+		if len(rv) == 0 || rv[len(rv)-1].Date != date {
+			rv = append(rv, VendorZoneDay{
+				Date:   date,
+				Qtype:  map[string]uint64{},
+				UserCC: map[string]uint64{},
+			})
+		}
+
+		last := &rv[len(rv)-1]
+		last.Queries += queries
+		last.Qtype[Qtype] += queries
+		last.UserCC[UserCC] += queries
+	}
+	if err := rows.Err(); err != nil {
+		return nil, queryError(ctx, span, log, err)
 	}
 
-	return r, nil
+	return rv, nil
 }
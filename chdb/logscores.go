@@ -2,182 +2,102 @@ package chdb
 
 import (
 	"context"
-	"fmt"
-	"strings"
 	"time"
 
-	"github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
 	"go.ntppool.org/common/logger"
-	"go.ntppool.org/common/tracing"
 	"go.ntppool.org/data-api/ntpdb"
 )
 
-func (d *ClickHouse) Logscores(ctx context.Context, serverID, monitorID int, since time.Time, limit int, fullHistory bool) ([]ntpdb.LogScore, error) {
-	log := logger.Setup()
-	ctx, span := tracing.Tracer().Start(ctx, "CH Logscores")
-	defer span.End()
+// LogscoresQuery selects log scores for a server. Zero values for
+// the monitor, times and limit don't filter the results.
+type LogscoresQuery struct {
+	ServerID  int
+	MonitorID int
 
-	recentFirst := true
+	// After excludes log scores at or before this time
+	After time.Time
+	// To excludes log scores after this time
+	To time.Time
 
-	if since.IsZero() && !fullHistory {
-		since = time.Now().Add(4 * -24 * time.Hour)
-	} else {
-		recentFirst = false
-	}
+	Limit       int
+	RecentFirst bool
+}
 
-	args := []interface{}{serverID}
-	query := `select id,monitor_id,server_id,ts,
-                toFloat64(score),toFloat64(step),offset,
-                rtt,leap,warning,error
-              from log_scores
-              where
-                server_id = ?`
+// where returns the conditions and arguments for the query
+func (q LogscoresQuery) where() (string, []interface{}) {
+	where := "server_id = ?"
+	args := []interface{}{q.ServerID}
 
-	if monitorID > 0 {
-		query = `select id,monitor_id,server_id,ts,
-                toFloat64(score),toFloat64(step),offset,
-                rtt,leap,warning,error
-              from log_scores
-              where
-                server_id = ?
-                and monitor_id = ?`
-		args = []interface{}{serverID, monitorID}
+	if q.MonitorID > 0 {
+		where += " and monitor_id = ?"
+		args = append(args, q.MonitorID)
 	}
-
-	if fullHistory {
-		query += " order by ts"
-		if recentFirst {
-			query += " desc"
-		}
-	} else {
-		query += " and ts > ? order by ts "
-		if recentFirst {
-			query += "desc "
-		}
-		query += "limit ?"
-		args = append(args, since, limit)
+	if !q.After.IsZero() {
+		where += " and ts > ?"
+		args = append(args, q.After)
 	}
-
-	log.DebugContext(ctx, "clickhouse query", "query", query, "args", args)
-
-	rows, err := d.Scores.Query(
-		clickhouse.Context(
-			ctx, clickhouse.WithSpan(span.SpanContext()),
-		),
-		query, args...,
-	)
-	if err != nil {
-		log.ErrorContext(ctx, "query error", "err", err)
-		return nil, fmt.Errorf("database error")
+	if !q.To.IsZero() {
+		where += " and ts <= ?"
+		args = append(args, q.To)
 	}
 
-	rv := []ntpdb.LogScore{}
-
-	for rows.Next() {
-
-		row := ntpdb.LogScore{}
-
-		var leap uint8
-
-		if err := rows.Scan(
-			&row.ID,
-			&row.MonitorID,
-			&row.ServerID,
-			&row.Ts,
-			&row.Score,
-			&row.Step,
-			&row.Offset,
-			&row.Rtt,
-			&leap,
-			&row.Attributes.Warning,
-			&row.Attributes.Error,
-		); err != nil {
-			log.Error("could not parse row", "err", err)
-			continue
-		}
-
-		row.Attributes.Leap = int8(leap)
-
-		rv = append(rv, row)
+	return where, args
+}
 
+// orderLimit returns the order and limit clauses for the query
+func (q LogscoresQuery) orderLimit(args []interface{}) (string, []interface{}) {
+	s := " order by ts"
+	if q.RecentFirst {
+		s += " desc"
 	}
-
-	// log.InfoContext(ctx, "returning data", "rv", rv)
-
-	return rv, nil
+	if q.Limit > 0 {
+		s += " limit ?"
+		args = append(args, q.Limit)
+	}
+	return s, args
 }
 
-// LogscoresTimeRange queries log scores within a specific time range for Grafana integration
-func (d *ClickHouse) LogscoresTimeRange(ctx context.Context, serverID, monitorID int, from, to time.Time, limit int) ([]ntpdb.LogScore, error) {
+func (d *ClickHouse) Logscores(ctx context.Context, q LogscoresQuery) ([]ntpdb.LogScore, error) {
 	log := logger.Setup()
-	ctx, span := tracing.Tracer().Start(ctx, "CH LogscoresTimeRange")
+	ctx, span := startQuery(ctx, "CH Logscores")
 	defer span.End()
 
-	args := []interface{}{serverID, from, to}
-	
+	where, args := q.where()
 	query := `select id,monitor_id,server_id,ts,
                 toFloat64(score),toFloat64(step),offset,
                 rtt,leap,warning,error
               from log_scores
-              where
-                server_id = ?
-                and ts >= ?
-                and ts <= ?`
-
-	if monitorID > 0 {
-		query += " and monitor_id = ?"
-		args = append(args, monitorID)
-	}
+              where ` + where
 
-	// Always order by timestamp ASC for Grafana convention
-	query += " order by ts ASC"
-	
-	// Apply limit to prevent memory issues
-	if limit > 0 {
-		query += " limit ?"
-		args = append(args, limit)
-	}
+	orderLimit, args := q.orderLimit(args)
+	query += orderLimit
 
-	log.DebugContext(ctx, "clickhouse time range query", 
-		"query", query, 
-		"args", args,
-		"server_id", serverID,
-		"monitor_id", monitorID,
-		"from", from.Format(time.RFC3339),
-		"to", to.Format(time.RFC3339),
-		"limit", limit,
-		"full_sql_with_params", func() string {
-			// Build a readable SQL query with parameters substituted for debugging
-			sqlDebug := query
-			paramIndex := 0
-			for strings.Contains(sqlDebug, "?") && paramIndex < len(args) {
-				var replacement string
-				switch v := args[paramIndex].(type) {
-				case int:
-					replacement = fmt.Sprintf("%d", v)
-				case time.Time:
-					replacement = fmt.Sprintf("'%s'", v.Format("2006-01-02 15:04:05"))
-				default:
-					replacement = fmt.Sprintf("'%v'", v)
-				}
-				sqlDebug = strings.Replace(sqlDebug, "?", replacement, 1)
-				paramIndex++
-			}
-			return sqlDebug
-		}(),
-	)
+	log.DebugContext(ctx, "clickhouse query", "query", query, "args", args)
 
-	rows, err := d.Scores.Query(
-		clickhouse.Context(
-			ctx, clickhouse.WithSpan(span.SpanContext()),
-		),
+	rows, err := d.Scores().Query(
+		queryContext(ctx, span),
 		query, args...,
 	)
 	if err != nil {
-		log.ErrorContext(ctx, "time range query error", "err", err)
-		return nil, fmt.Errorf("database error")
+		return nil, queryError(ctx, span, log, err)
 	}
 
+	rv, err := scanLogScores(ctx, rows)
+	if err != nil {
+		return nil, queryError(ctx, span, log, err)
+	}
+
+	// log.InfoContext(ctx, "returning data", "rv", rv)
+
+	return rv, nil
+}
+
+// scanLogScores reads the rows from a log_scores query selecting
+// id,monitor_id,server_id,ts,score,step,offset,rtt,leap,warning,error
+func scanLogScores(ctx context.Context, rows driver.Rows) ([]ntpdb.LogScore, error) {
+	log := logger.FromContext(ctx)
+
 	rv := []ntpdb.LogScore{}
 
 	for rows.Next() {
@@ -205,28 +125,5 @@ func (d *ClickHouse) LogscoresTimeRange(ctx context.Context, serverID, monitorID
 		rv = append(rv, row)
 	}
 
-	log.InfoContext(ctx, "time range query results", 
-		"rows_returned", len(rv),
-		"server_id", serverID,
-		"monitor_id", monitorID,
-		"time_range", fmt.Sprintf("%s to %s", from.Format(time.RFC3339), to.Format(time.RFC3339)),
-		"limit", limit,
-		"sample_rows", func() []map[string]interface{} {
-			samples := make([]map[string]interface{}, 0, 3)
-			for i, row := range rv {
-				if i >= 3 { break }
-				samples = append(samples, map[string]interface{}{
-					"id": row.ID,
-					"monitor_id": row.MonitorID,
-					"ts": row.Ts.Format(time.RFC3339),
-					"score": row.Score,
-					"rtt_valid": row.Rtt.Valid,
-					"offset_valid": row.Offset.Valid,
-				})
-			}
-			return samples
-		}(),
-	)
-
-	return rv, nil
+	return rv, rows.Err()
 }
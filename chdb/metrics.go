@@ -0,0 +1,109 @@
+package chdb
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"go.ntppool.org/common/tracing"
+)
+
+var (
+	queryReadRows = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "dataapi_clickhouse_query_read_rows",
+		Help:    "Rows read by ClickHouse for a query",
+		Buckets: prometheus.ExponentialBuckets(100, 10, 9),
+	}, []string{"query"})
+
+	queryReadBytes = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "dataapi_clickhouse_query_read_bytes",
+		Help:    "Bytes read by ClickHouse for a query",
+		Buckets: prometheus.ExponentialBuckets(1024, 8, 9),
+	}, []string{"query"})
+
+	queryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "dataapi_clickhouse_query_duration_seconds",
+		Help:    "ClickHouse query time",
+		Buckets: prometheus.ExponentialBuckets(0.005, 3, 10),
+	}, []string{"query"})
+)
+
+// RegisterMetrics adds the ClickHouse query metrics to the registry.
+func RegisterMetrics(reg prometheus.Registerer) error {
+	for _, c := range []prometheus.Collector{queryReadRows, queryReadBytes, queryDuration} {
+		err := reg.Register(c)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// querySpan is the span for a ClickHouse query; it collects the
+// progress and profile info ClickHouse sends for the queries made
+// with queryContext and records them on the span and in the metrics
+// when the span ends.
+type querySpan struct {
+	trace.Span
+
+	name  string
+	start time.Time
+
+	mu         sync.Mutex
+	readRows   uint64
+	readBytes  uint64
+	resultRows uint64
+	elapsed    time.Duration
+}
+
+// startQuery starts the span for the named query.
+func startQuery(ctx context.Context, name string) (context.Context, *querySpan) {
+	ctx, span := tracing.Tracer().Start(ctx, name)
+	return ctx, &querySpan{Span: span, name: name, start: time.Now()}
+}
+
+func (s *querySpan) progress(p *clickhouse.Progress) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.readRows += p.Rows
+	s.readBytes += p.Bytes
+	if p.Elapsed > s.elapsed {
+		s.elapsed = p.Elapsed
+	}
+}
+
+func (s *querySpan) profileInfo(p *clickhouse.ProfileInfo) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.resultRows += p.Rows
+}
+
+// End records the query statistics and ends the span.
+func (s *querySpan) End(options ...trace.SpanEndOption) {
+	s.mu.Lock()
+	elapsed := s.elapsed
+	if elapsed == 0 {
+		// older servers don't send the elapsed time
+		elapsed = time.Since(s.start)
+	}
+	readRows, readBytes, resultRows := s.readRows, s.readBytes, s.resultRows
+	s.mu.Unlock()
+
+	s.SetAttributes(
+		attribute.Int64("clickhouse.read_rows", int64(readRows)),
+		attribute.Int64("clickhouse.read_bytes", int64(readBytes)),
+		attribute.Int64("clickhouse.result_rows", int64(resultRows)),
+		attribute.Float64("clickhouse.elapsed", elapsed.Seconds()),
+	)
+
+	queryReadRows.WithLabelValues(s.name).Observe(float64(readRows))
+	queryReadBytes.WithLabelValues(s.name).Observe(float64(readBytes))
+	queryDuration.WithLabelValues(s.name).Observe(elapsed.Seconds())
+
+	s.Span.End(options...)
+}
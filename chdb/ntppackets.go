@@ -0,0 +1,84 @@
+package chdb
+
+// queries to the optional NTP packet counts
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.ntppool.org/common/logger"
+)
+
+// NTPPacketCounts is the NTP packets to a server in the bucket
+// starting at T, in total and on average per second.
+type NTPPacketCounts struct {
+	T       uint32  `json:"t"`
+	Packets uint64  `json:"packets"`
+	Avg     float64 `json:"avg"`
+}
+
+// NTPPacketsEnabled returns true if the NTP packet counts are
+// configured.
+func (d *ClickHouse) NTPPacketsEnabled() bool {
+	return d.NTP() != nil
+}
+
+// NTPPackets returns the NTP packets to the server IP in buckets of
+// step from 'from' up to 'to'.
+func (d *ClickHouse) NTPPackets(ctx context.Context, serverIP string, from, to time.Time, step time.Duration) ([]NTPPacketCounts, error) {
+	log := logger.Setup().With("server", serverIP)
+	ctx, span := startQuery(ctx, "NTPPackets")
+	defer span.End()
+
+	conns := d.conns.Load()
+	if conns.ntp == nil {
+		return nil, fmt.Errorf("ntp packets not configured")
+	}
+
+	stepSeconds := int64(step.Seconds())
+	if stepSeconds < 1 {
+		return nil, fmt.Errorf("invalid step %s", step)
+	}
+
+	startUnix := from.Unix()
+	startUnix -= startUnix % stepSeconds
+
+	rows, err := conns.ntp.Query(queryContext(ctx, span),
+		`
+	select toUnixTimestamp(toStartOfInterval(Time, INTERVAL ? SECOND)) as t,
+		sum(Packets) as packets
+	from `+conns.ntpConfig.Table+`
+	where
+		ServerIP = ?
+		and Time >= FROM_UNIXTIME(?)
+		and Time < FROM_UNIXTIME(?)
+	group by t order by t
+`, stepSeconds, serverIP, startUnix, to.Unix())
+	if err != nil {
+		return nil, queryError(ctx, span, log, err)
+	}
+
+	rv := []NTPPacketCounts{}
+
+	for rows.Next() {
+		var (
+			t       uint32
+			packets uint64
+		)
+		if err := rows.Scan(&t, &packets); err != nil {
+			log.ErrorContext(ctx, "could not parse row", "err", err)
+			continue
+		}
+		rv = append(rv, NTPPacketCounts{
+			T:       t,
+			Packets: packets,
+			Avg:     float64(packets) / float64(stepSeconds),
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, queryError(ctx, span, log, err)
+	}
+
+	return rv, nil
+}
@@ -0,0 +1,93 @@
+package chdb
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestNTPPackets runs the NTP packet counts query on the table and
+// rows in testdata/ntp_packets.sql. It needs a ClickHouse database
+// the test can create tables in, set with DATA_API_TEST_CLICKHOUSE_DSN.
+func TestNTPPackets(t *testing.T) {
+	dsn := os.Getenv("DATA_API_TEST_CLICKHOUSE_DSN")
+	if len(dsn) == 0 {
+		t.Skip("DATA_API_TEST_CLICKHOUSE_DSN not set")
+	}
+
+	ctx := context.Background()
+
+	options, err := clickhouseOptions(DBConfig{DSN: dsn})
+	if err != nil {
+		t.Fatalf("options: %s", err)
+	}
+	conn, err := openConn(ctx, options)
+	if err != nil {
+		t.Fatalf("open: %s", err)
+	}
+	defer conn.Close()
+
+	schema, err := os.ReadFile("testdata/ntp_packets.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = conn.Exec(ctx, "DROP TABLE IF EXISTS ntp_packets_1m")
+	if err != nil {
+		t.Fatalf("drop table: %s", err)
+	}
+	for _, stmt := range sqlStatements(string(schema)) {
+		if err := conn.Exec(ctx, stmt); err != nil {
+			t.Fatalf("%s: %s", stmt, err)
+		}
+	}
+
+	ch := &ClickHouse{}
+	ch.conns.Store(&connections{
+		ntp:       conn,
+		ntpConfig: NTPConfig{Enabled: true, Table: "ntp_packets_1m"},
+	})
+
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(4 * time.Minute)
+
+	counts, err := ch.NTPPackets(ctx, "192.0.2.10", from, to, 2*time.Minute)
+	if err != nil {
+		t.Fatalf("NTPPackets: %s", err)
+	}
+
+	want := []NTPPacketCounts{
+		{T: uint32(from.Unix()), Packets: 12100, Avg: 12100.0 / 120},
+		{T: uint32(from.Add(2 * time.Minute).Unix()), Packets: 5900, Avg: 5900.0 / 120},
+	}
+	if len(counts) != len(want) {
+		t.Fatalf("got %d buckets, expected %d: %+v", len(counts), len(want), counts)
+	}
+	for i := range want {
+		if counts[i] != want[i] {
+			t.Errorf("bucket %d: got %+v, expected %+v", i, counts[i], want[i])
+		}
+	}
+}
+
+// sqlStatements splits the SQL file into statements, without the
+// comment lines
+func sqlStatements(s string) []string {
+	lines := []string{}
+	for _, line := range strings.Split(s, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "--") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+
+	stmts := []string{}
+	for _, stmt := range strings.Split(strings.Join(lines, "\n"), ";") {
+		if stmt = strings.TrimSpace(stmt); len(stmt) > 0 {
+			stmts = append(stmts, stmt)
+		}
+	}
+	return stmts
+}
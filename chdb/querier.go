@@ -0,0 +1,82 @@
+package chdb
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.ntppool.org/data-api/ntpdb"
+)
+
+// Querier is the ClickHouse queries used by the API server, so
+// the handlers can run against an in-memory implementation.
+type Querier interface {
+	ServerAnswerCounts(ctx context.Context, serverIP string, days int) (ServerQueries, error)
+	AnswerTotals(ctx context.Context, qtype string, days int) (ServerTotals, error)
+	ServersAnswerCounts(ctx context.Context, serverIPs []string, days int) (map[string]ServerQueries, error)
+	AnswerTotalsByQtype(ctx context.Context, qtypes []string, days int) (map[string]ServerTotals, error)
+	ServerAnswerSeries(ctx context.Context, serverIP string, from, to time.Time) ([]ServerQueriesDay, error)
+	AnswerTotalsSeries(ctx context.Context, qtype string, from, to time.Time) (map[string]ServerTotals, error)
+	UserCountryData(ctx context.Context) (*UserCountry, error)
+	UserCountrySeries(ctx context.Context, from, to time.Time) ([]UserCountryDay, error)
+	DNSQueries(ctx context.Context, r DNSQueryRange) ([]DNSQueryCounts, error)
+	VendorZoneQueries(ctx context.Context, origin, label string, from, to time.Time) ([]VendorZoneDay, error)
+
+	Logscores(ctx context.Context, q LogscoresQuery) ([]ntpdb.LogScore, error)
+
+	ArchiveEnabled() bool
+	ArchiveCutoff() time.Time
+	ArchiveLogscores(ctx context.Context, q LogscoresQuery) ([]ntpdb.LogScore, error)
+
+	NTPPacketsEnabled() bool
+	NTPPackets(ctx context.Context, serverIP string, from, to time.Time, step time.Duration) ([]NTPPacketCounts, error)
+
+	NewestTime(ctx context.Context, table DataTable) (time.Time, error)
+
+	PingScores(ctx context.Context) error
+	HostStatus(ctx context.Context, database string) []HostStatus
+}
+
+var _ Querier = (*ClickHouse)(nil)
+
+func (d *ClickHouse) PingScores(ctx context.Context) error {
+	return d.Scores().Ping(ctx)
+}
+
+// HostStatus is the status of one of the hosts of a ClickHouse
+// database
+type HostStatus struct {
+	Database string
+	Host     string
+	OK       bool
+	Error    string `json:",omitempty"`
+}
+
+// HostStatus pings each of the configured hosts for the database
+// ("scores" or "logs").
+func (d *ClickHouse) HostStatus(ctx context.Context, database string) []HostStatus {
+	hosts := []hostConn{}
+	for _, h := range d.conns.Load().hosts {
+		if h.database == database {
+			hosts = append(hosts, h)
+		}
+	}
+
+	rv := make([]HostStatus, len(hosts))
+
+	var wg sync.WaitGroup
+	for i, h := range hosts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rv[i] = HostStatus{Database: h.database, Host: h.addr, OK: true}
+			if err := h.conn.Ping(ctx); err != nil {
+				rv[i].OK = false
+				rv[i].Error = err.Error()
+			}
+		}()
+	}
+	wg.Wait()
+
+	return rv
+}
@@ -0,0 +1,128 @@
+package chdb
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"go.ntppool.org/common/logger"
+)
+
+// closeDelay is how long the replaced connections are kept open
+// after a reload so queries in progress can finish
+const closeDelay = 2 * time.Minute
+
+// Reload reads the configuration file and opens new connections with
+// it. If the configuration is invalid or the databases can't be
+// reached the error is returned and the current connections are kept.
+func (d *ClickHouse) Reload(ctx context.Context) error {
+	log := logger.FromContext(ctx)
+
+	conns, err := setupClickhouse(ctx, d.configFile)
+	if err != nil {
+		return err
+	}
+
+	old := d.conns.Swap(conns)
+	log.InfoContext(ctx, "clickhouse configuration reloaded", "file", d.configFile)
+
+	time.AfterFunc(closeDelay, func() {
+		if err := old.close(); err != nil {
+			log.Warn("closing old clickhouse connections", "err", err)
+		}
+	})
+
+	return nil
+}
+
+// reloadDelay is how long Watch waits after a change to the
+// configuration file before reloading, so an editor or a ConfigMap
+// update writing the file in several steps is one reload
+const reloadDelay = time.Second
+
+// Watch reloads the configuration when the process gets a SIGHUP or
+// the configuration file is written or replaced, until the context
+// is done. The directory is watched so a file replaced by a rename
+// (or a Kubernetes ConfigMap symlink swap) is noticed.
+func (d *ClickHouse) Watch(ctx context.Context) error {
+	log := logger.FromContext(ctx).With("file", d.configFile)
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("watching clickhouse configuration: %w", err)
+	}
+	defer watcher.Close()
+
+	file := filepath.Clean(d.configFile)
+	if err := watcher.Add(filepath.Dir(file)); err != nil {
+		return fmt.Errorf("watching clickhouse configuration: %w", err)
+	}
+
+	// the file the configuration path points to, to notice when a
+	// symlink in the path is changed
+	realPath := func() string {
+		p, err := filepath.EvalSymlinks(file)
+		if err != nil {
+			return ""
+		}
+		return p
+	}
+	lastPath := realPath()
+
+	reload := time.NewTimer(reloadDelay)
+	reload.Stop()
+	defer reload.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case <-hup:
+			log.InfoContext(ctx, "reloading clickhouse configuration (SIGHUP)")
+
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			changed := filepath.Clean(event.Name) == file &&
+				event.Has(fsnotify.Create|fsnotify.Write|fsnotify.Rename)
+			if p := realPath(); len(p) > 0 && p != lastPath {
+				lastPath = p
+				changed = true
+			}
+			if changed {
+				reload.Reset(reloadDelay)
+			}
+			continue
+
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			log.WarnContext(ctx, "watching clickhouse configuration", "err", err)
+			continue
+
+		case <-reload.C:
+			if _, err := os.Stat(file); err != nil {
+				log.WarnContext(ctx, "clickhouse configuration changed but can't be read", "err", err)
+				continue
+			}
+			log.InfoContext(ctx, "reloading clickhouse configuration (file changed)")
+		}
+
+		err := d.Reload(ctx)
+		if err != nil {
+			log.ErrorContext(ctx, "could not reload clickhouse configuration; keeping the current connections", "err", err)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+-- Daily rollup of the geodns queries by origin and label name, for
+-- the vendor zone queries. It's filled by the materialized view from
+-- the queries table as the logs are inserted; to include older days,
+-- insert the same select from queries for those days.
+
+CREATE TABLE IF NOT EXISTS by_origin_label_1d
+(
+    `dt` Date,
+    `Origin` LowCardinality(String),
+    `LabelName` String,
+    `Qtype` LowCardinality(String),
+    `UserCC` LowCardinality(String),
+    `queries` UInt64
+)
+ENGINE = SummingMergeTree
+PARTITION BY toYYYYMM(dt)
+ORDER BY (Origin, LabelName, dt, Qtype, UserCC)
+TTL dt + INTERVAL 400 DAY;
+
+CREATE MATERIALIZED VIEW IF NOT EXISTS by_origin_label_1d_mv
+TO by_origin_label_1d
+AS SELECT
+    toDate(Time) AS dt,
+    Origin,
+    LabelName,
+    Qtype,
+    UserCC,
+    count() AS queries
+FROM queries
+GROUP BY dt, Origin, LabelName, Qtype, UserCC;
@@ -0,0 +1,22 @@
+-- Schema for the optional NTP packet counts table (clickhouse.ntp
+-- in the database config). The packets are counted by the servers
+-- or a collector and aggregated per minute; any table or view with
+-- these columns works.
+
+CREATE TABLE IF NOT EXISTS ntp_packets_1m
+(
+    `Time` DateTime,
+    `ServerIP` String,
+    `Packets` UInt64
+)
+ENGINE = SummingMergeTree
+PARTITION BY toYYYYMM(Time)
+ORDER BY (ServerIP, Time)
+TTL Time + INTERVAL 180 DAY;
+
+INSERT INTO ntp_packets_1m (Time, ServerIP, Packets) VALUES
+    ('2024-01-01 00:00:00', '192.0.2.10', 6000),
+    ('2024-01-01 00:01:00', '192.0.2.10', 6100),
+    ('2024-01-01 00:02:00', '192.0.2.10', 5900),
+    ('2024-01-01 00:00:00', '2001:db8::10', 1200),
+    ('2024-01-01 00:01:00', '2001:db8::10', 1300);
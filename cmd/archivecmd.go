@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.ntppool.org/common/logger"
+	"go.ntppool.org/common/version"
+
+	"go.ntppool.org/data-api/archiver"
+	"go.ntppool.org/data-api/chdb"
+	"go.ntppool.org/data-api/ntpdb"
+)
+
+func (cli *CLI) archiveCmd() *cobra.Command {
+
+	var archiveCmd = &cobra.Command{
+		Use:   "archive",
+		Short: "archive copies log_scores from MySQL to ClickHouse",
+		Long: `archive reads log_scores from MySQL past the position recorded in
+log_scores_archive_status and inserts them into ClickHouse, optionally
+also exporting them to the Parquet archive.`,
+		RunE: cli.archiveCLI,
+	}
+
+	archiveCmd.Flags().String("archiver", "clickhouse", "archiver name in log_scores_archive_status")
+	archiveCmd.Flags().Int("batch-size", 10000, "log scores per batch")
+	archiveCmd.Flags().Duration("min-age", 10*time.Minute, "don't archive log scores newer than this")
+	archiveCmd.Flags().Duration("interval", 0, "keep running and archive new log scores at this interval")
+	archiveCmd.Flags().Bool("parquet", false, "also export to the archive export target")
+
+	return archiveCmd
+}
+
+func (cli *CLI) archiveCLI(cmd *cobra.Command, args []string) error {
+	log := logger.Setup()
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	log.Info("starting archiver", "version", version.Version())
+
+	flags := cmd.Flags()
+	opts := archiver.Options{}
+	opts.Archiver, _ = flags.GetString("archiver")
+	opts.BatchSize, _ = flags.GetInt("batch-size")
+	opts.MinAge, _ = flags.GetDuration("min-age")
+	opts.Export, _ = flags.GetBool("parquet")
+	interval, _ := flags.GetDuration("interval")
+
+	ch, err := chdb.New(ctx, cfgFile)
+	if err != nil {
+		return fmt.Errorf("clickhouse open: %w", err)
+	}
+	db, err := ntpdb.OpenDB(ctx, cfgFile)
+	if err != nil {
+		return fmt.Errorf("mysql open: %w", err)
+	}
+	defer db.Close()
+
+	a := archiver.New(db, ch, opts)
+
+	err = a.Run(ctx, interval)
+	if err != nil {
+		log.Error("archiver error", "err", err)
+		return err
+	}
+
+	return nil
+}
@@ -12,6 +12,10 @@ import (
 	"github.com/spf13/cobra"
 	"go.ntppool.org/common/logger"
 	"go.ntppool.org/common/version"
+
+	"go.ntppool.org/data-api/chdb"
+	"go.ntppool.org/data-api/envconfig"
+	"go.ntppool.org/data-api/ntpdb"
 )
 
 var cfgFile string
@@ -39,6 +43,7 @@ func (cli *CLI) rootCmd() *cobra.Command {
 	}
 
 	cmd.AddCommand(cli.serverCmd())
+	cmd.AddCommand(cli.archiveCmd())
 	cmd.AddCommand(version.VersionCmd("data-api"))
 
 	return cmd
@@ -62,6 +67,20 @@ func (cli *CLI) init(cmd *cobra.Command) {
 
 	cmd.PersistentFlags().StringVar(&cfgFile, "database-config", "database.yaml", "config file (default is $HOME/.data-api.yaml)")
 
+	configFlags := append(ntpdb.ConfigFlags(), chdb.ConfigFlags()...)
+	for _, flag := range configFlags {
+		cmd.PersistentFlags().String(flag.Name, "", flag.Usage)
+	}
+	cmd.PersistentPreRun = func(cmd *cobra.Command, args []string) {
+		// the flags are applied each time the configuration is
+		// read, after the configuration file and the environment
+		for _, flag := range configFlags {
+			if f := cmd.Flags().Lookup(flag.Name); f != nil && f.Changed {
+				envconfig.SetFlag(flag.Name, f.Value.String())
+			}
+		}
+	}
+
 	// Cobra also supports local flags, which will only run
 	// when this action is called directly.
 	cmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
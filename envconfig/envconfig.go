@@ -0,0 +1,121 @@
+// Package envconfig reads the database configuration overrides from
+// the environment. Each setting can be given directly (for example
+// DATA_API_MYSQL_PASSWORD) or as the path of a file with the value
+// in the variable with a _FILE suffix (DATA_API_MYSQL_PASSWORD_FILE),
+// for secrets mounted as files.
+//
+// The configuration is layered: the YAML file, then the environment,
+// then the command line flags (see SetFlag and ApplyFlags). The
+// environment is read again each time the configuration file is, so
+// changes to the secret files are picked up with the rest of the
+// configuration.
+package envconfig
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Prefix is the prefix of the environment variables
+const Prefix = "DATA_API_"
+
+// Lookup returns the value of the environment variable Prefix+name
+// or of the file named in Prefix+name+"_FILE" (with the trailing
+// newline removed). It's an error if both are set.
+func Lookup(name string) (string, bool, error) {
+	name = Prefix + name
+
+	v, ok := os.LookupEnv(name)
+	fileName, fileOK := os.LookupEnv(name + "_FILE")
+
+	switch {
+	case ok && fileOK:
+		return "", false, fmt.Errorf("only one of %s and %s_FILE can be set", name, name)
+	case fileOK:
+		b, err := os.ReadFile(fileName)
+		if err != nil {
+			return "", false, fmt.Errorf("%s_FILE: %w", name, err)
+		}
+		return strings.TrimRight(string(b), "\r\n"), true, nil
+	default:
+		return v, ok, nil
+	}
+}
+
+// Override sets each of the strings to the value of its environment
+// variable (see Lookup) if it's set.
+func Override(vars map[string]*string) error {
+	for name, s := range vars {
+		v, ok, err := Lookup(name)
+		if err != nil {
+			return err
+		}
+		if ok {
+			*s = v
+		}
+	}
+	return nil
+}
+
+var (
+	flagLock   sync.RWMutex
+	flagValues = map[string]string{}
+)
+
+// SetFlag sets the value of a command line flag for the
+// configuration, by flag name (for example "database.pass"). The
+// value is kept in memory (not in the environment) and applied by
+// ApplyFlags each time the configuration is read.
+func SetFlag(name, value string) {
+	flagLock.Lock()
+	defer flagLock.Unlock()
+	flagValues[name] = value
+}
+
+// ApplyFlags sets the string fields of the struct cfg points to that
+// have a `flag` struct tag to the value of the flag prefix+tag, if
+// it was set with SetFlag.
+func ApplyFlags(prefix string, cfg any) {
+	flagLock.RLock()
+	defer flagLock.RUnlock()
+
+	v := reflect.ValueOf(cfg).Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		name, ok := t.Field(i).Tag.Lookup("flag")
+		if !ok || t.Field(i).Type.Kind() != reflect.String {
+			continue
+		}
+		if value, ok := flagValues[prefix+name]; ok {
+			v.Field(i).SetString(value)
+		}
+	}
+}
+
+// Flag is a command line flag for a configuration setting
+type Flag struct {
+	Name  string
+	Usage string
+}
+
+// Flags returns the flags (with the prefix) for the string fields
+// with a `flag` struct tag in the struct cfg points to; the usage is
+// from the `usage` tag.
+func Flags(prefix string, cfg any) []Flag {
+	t := reflect.TypeOf(cfg).Elem()
+	flags := []Flag{}
+	for i := 0; i < t.NumField(); i++ {
+		name, ok := t.Field(i).Tag.Lookup("flag")
+		if !ok || t.Field(i).Type.Kind() != reflect.String {
+			continue
+		}
+		flags = append(flags, Flag{
+			Name:  prefix + name,
+			Usage: t.Field(i).Tag.Get("usage"),
+		})
+	}
+	return flags
+}
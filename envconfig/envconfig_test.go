@@ -0,0 +1,36 @@
+package envconfig
+
+import "testing"
+
+func TestApplyFlags(t *testing.T) {
+	type config struct {
+		DSN   string `flag:"dsn"`
+		Pass  string `flag:"pass"`
+		Other string
+	}
+
+	t.Setenv(Prefix+"TEST_DSN", "env-dsn")
+	t.Setenv(Prefix+"TEST_PASS", "env-pass")
+
+	cfg := config{DSN: "yaml-dsn", Pass: "yaml-pass", Other: "yaml"}
+	err := Override(map[string]*string{
+		"TEST_DSN":  &cfg.DSN,
+		"TEST_PASS": &cfg.Pass,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	SetFlag("test.pass", "flag-pass")
+	ApplyFlags("test.", &cfg)
+
+	want := config{DSN: "env-dsn", Pass: "flag-pass", Other: "yaml"}
+	if cfg != want {
+		t.Errorf("got %+v, expected %+v", cfg, want)
+	}
+
+	flags := Flags("test.", &cfg)
+	if len(flags) != 2 || flags[0].Name != "test.dsn" || flags[1].Name != "test.pass" {
+		t.Errorf("unexpected flags %+v", flags)
+	}
+}
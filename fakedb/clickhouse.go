@@ -0,0 +1,248 @@
+package fakedb
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.ntppool.org/data-api/chdb"
+	"go.ntppool.org/data-api/ntpdb"
+)
+
+// ClickHouse is an in-memory chdb.Querier
+type ClickHouse struct {
+	// ServerAnswers is the result of ServerAnswerCounts by server IP
+	ServerAnswers map[string]chdb.ServerQueries `json:"server_answers"`
+	// Totals is the result of AnswerTotals by query type
+	Totals map[string]chdb.ServerTotals `json:"answer_totals"`
+
+	// ServerAnswerDays is the daily answers by server IP
+	ServerAnswerDays map[string][]chdb.ServerQueriesDay `json:"server_answer_days"`
+	// TotalsDays is the daily answer totals by query type and date
+	TotalsDays map[string]map[string]chdb.ServerTotals `json:"answer_totals_days"`
+
+	UserCountry     *chdb.UserCountry     `json:"user_country"`
+	UserCountryDays []chdb.UserCountryDay `json:"user_country_days"`
+	DNSQueryCounts  []chdb.DNSQueryCounts `json:"dns_queries"`
+
+	// VendorZoneDays is the daily queries by vendor zone name
+	// (label and origin, like "example.pool.ntp.org")
+	VendorZoneDays map[string][]chdb.VendorZoneDay `json:"vendor_zone_days"`
+
+	LogScores []ntpdb.LogScore `json:"log_scores"`
+
+	// Archive is the offline log_scores archive; it's only used if
+	// ArchiveCutoffTime is set.
+	Archive           []ntpdb.LogScore `json:"archive"`
+	ArchiveCutoffTime time.Time        `json:"archive_cutoff"`
+
+	// NTPPacketCounts is the NTP packets by server IP; the NTP
+	// packet counts are enabled if it's set
+	NTPPacketCounts map[string][]chdb.NTPPacketCounts `json:"ntp_packets"`
+
+	// NewestTimes is the result of NewestTime by table
+	NewestTimes map[chdb.DataTable]time.Time `json:"newest_times"`
+
+	// Err is returned from every query and ping when set
+	Err error `json:"-"`
+}
+
+var _ chdb.Querier = (*ClickHouse)(nil)
+
+func (d *ClickHouse) ServerAnswerCounts(ctx context.Context, serverIP string, days int) (chdb.ServerQueries, error) {
+	if d.Err != nil {
+		return nil, d.Err
+	}
+	return copyQueries(d.ServerAnswers[serverIP]), nil
+}
+
+func (d *ClickHouse) AnswerTotals(ctx context.Context, qtype string, days int) (chdb.ServerTotals, error) {
+	if d.Err != nil {
+		return nil, d.Err
+	}
+	return d.Totals[qtype], nil
+}
+
+func (d *ClickHouse) ServersAnswerCounts(ctx context.Context, serverIPs []string, days int) (map[string]chdb.ServerQueries, error) {
+	if d.Err != nil {
+		return nil, d.Err
+	}
+	rv := map[string]chdb.ServerQueries{}
+	for _, ip := range serverIPs {
+		if q, ok := d.ServerAnswers[ip]; ok {
+			rv[ip] = copyQueries(q)
+		}
+	}
+	return rv, nil
+}
+
+func (d *ClickHouse) AnswerTotalsByQtype(ctx context.Context, qtypes []string, days int) (map[string]chdb.ServerTotals, error) {
+	if d.Err != nil {
+		return nil, d.Err
+	}
+	rv := map[string]chdb.ServerTotals{}
+	for _, qtype := range qtypes {
+		if t, ok := d.Totals[qtype]; ok {
+			rv[qtype] = t
+		}
+	}
+	return rv, nil
+}
+
+func (d *ClickHouse) ServerAnswerSeries(ctx context.Context, serverIP string, from, to time.Time) ([]chdb.ServerQueriesDay, error) {
+	if d.Err != nil {
+		return nil, d.Err
+	}
+	rv := []chdb.ServerQueriesDay{}
+	for _, day := range d.ServerAnswerDays[serverIP] {
+		if inDateRange(day.Date, from, to) {
+			rv = append(rv, chdb.ServerQueriesDay{Date: day.Date, Server: copyQueries(day.Server)})
+		}
+	}
+	return rv, nil
+}
+
+func (d *ClickHouse) AnswerTotalsSeries(ctx context.Context, qtype string, from, to time.Time) (map[string]chdb.ServerTotals, error) {
+	if d.Err != nil {
+		return nil, d.Err
+	}
+	rv := map[string]chdb.ServerTotals{}
+	for date, totals := range d.TotalsDays[qtype] {
+		if inDateRange(date, from, to) {
+			rv[date] = totals
+		}
+	}
+	return rv, nil
+}
+
+// copyQueries returns a copy of the fixture data, as the handlers
+// set the points on the counts they get
+func copyQueries(q chdb.ServerQueries) chdb.ServerQueries {
+	if q == nil {
+		return nil
+	}
+	rv := make(chdb.ServerQueries, len(q))
+	for i, c := range q {
+		cc := *c
+		rv[i] = &cc
+	}
+	return rv
+}
+
+// inDateRange returns true if the YYYY-MM-DD date is on or between
+// the days of from and to
+func inDateRange(date string, from, to time.Time) bool {
+	return date >= from.Format(time.DateOnly) && date <= to.Format(time.DateOnly)
+}
+
+func (d *ClickHouse) UserCountryData(ctx context.Context) (*chdb.UserCountry, error) {
+	if d.Err != nil {
+		return nil, d.Err
+	}
+	return d.UserCountry, nil
+}
+
+func (d *ClickHouse) UserCountrySeries(ctx context.Context, from, to time.Time) ([]chdb.UserCountryDay, error) {
+	if d.Err != nil {
+		return nil, d.Err
+	}
+	rv := []chdb.UserCountryDay{}
+	for _, day := range d.UserCountryDays {
+		if inDateRange(day.Date, from, to) {
+			rv = append(rv, day)
+		}
+	}
+	return rv, nil
+}
+
+func (d *ClickHouse) DNSQueries(ctx context.Context, r chdb.DNSQueryRange) ([]chdb.DNSQueryCounts, error) {
+	if d.Err != nil {
+		return nil, d.Err
+	}
+	rv := []chdb.DNSQueryCounts{}
+	for _, q := range d.DNSQueryCounts {
+		if int64(q.T) >= r.From.Unix() && int64(q.T) < r.To.Unix() {
+			rv = append(rv, q)
+		}
+	}
+	return rv, nil
+}
+
+func (d *ClickHouse) VendorZoneQueries(ctx context.Context, origin, label string, from, to time.Time) ([]chdb.VendorZoneDay, error) {
+	if d.Err != nil {
+		return nil, d.Err
+	}
+	rv := []chdb.VendorZoneDay{}
+	for _, day := range d.VendorZoneDays[label+"."+origin] {
+		if inDateRange(day.Date, from, to) {
+			rv = append(rv, day)
+		}
+	}
+	return rv, nil
+}
+
+func (d *ClickHouse) Logscores(ctx context.Context, q chdb.LogscoresQuery) ([]ntpdb.LogScore, error) {
+	if d.Err != nil {
+		return nil, d.Err
+	}
+	return filterLogScores(d.LogScores, uint32(q.ServerID), uint32(q.MonitorID),
+		q.After, q.To, q.Limit, q.RecentFirst), nil
+}
+
+func (d *ClickHouse) ArchiveEnabled() bool {
+	return !d.ArchiveCutoffTime.IsZero()
+}
+
+func (d *ClickHouse) ArchiveCutoff() time.Time {
+	return d.ArchiveCutoffTime
+}
+
+func (d *ClickHouse) ArchiveLogscores(ctx context.Context, q chdb.LogscoresQuery) ([]ntpdb.LogScore, error) {
+	if d.Err != nil {
+		return nil, d.Err
+	}
+	if !d.ArchiveEnabled() {
+		return nil, fmt.Errorf("archive not configured")
+	}
+	return filterLogScores(d.Archive, uint32(q.ServerID), uint32(q.MonitorID),
+		q.After, q.To, q.Limit, q.RecentFirst), nil
+}
+
+func (d *ClickHouse) NTPPacketsEnabled() bool {
+	return d.NTPPacketCounts != nil
+}
+
+func (d *ClickHouse) NTPPackets(ctx context.Context, serverIP string, from, to time.Time, step time.Duration) ([]chdb.NTPPacketCounts, error) {
+	if d.Err != nil {
+		return nil, d.Err
+	}
+	if !d.NTPPacketsEnabled() {
+		return nil, fmt.Errorf("ntp packets not configured")
+	}
+	rv := []chdb.NTPPacketCounts{}
+	for _, c := range d.NTPPacketCounts[serverIP] {
+		if int64(c.T) >= from.Unix() && int64(c.T) < to.Unix() {
+			rv = append(rv, c)
+		}
+	}
+	return rv, nil
+}
+
+func (d *ClickHouse) NewestTime(ctx context.Context, table chdb.DataTable) (time.Time, error) {
+	if d.Err != nil {
+		return time.Time{}, d.Err
+	}
+	return d.NewestTimes[table], nil
+}
+
+func (d *ClickHouse) PingScores(ctx context.Context) error {
+	return d.Err
+}
+
+func (d *ClickHouse) HostStatus(ctx context.Context, database string) []chdb.HostStatus {
+	st := chdb.HostStatus{Database: database, Host: "fakedb", OK: d.Err == nil}
+	if d.Err != nil {
+		st.Error = d.Err.Error()
+	}
+	return []chdb.HostStatus{st}
+}
@@ -0,0 +1,89 @@
+// Package fakedb has in-memory implementations of the MySQL and
+// ClickHouse queries used by the API server, loaded from JSON
+// fixtures, so the handlers can be exercised with httptest.
+package fakedb
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"go.ntppool.org/data-api/ntpdb"
+)
+
+// Fixture is the data for the fake databases. The field names in
+// the JSON files are the json tags on the ntpdb and chdb types;
+// nullable columns are objects like {"String": "x", "Valid": true}.
+type Fixture struct {
+	MySQL      *DB         `json:"mysql"`
+	ClickHouse *ClickHouse `json:"clickhouse"`
+}
+
+// Load reads a fixture file. Either database can be left out of the
+// file and is then empty.
+func Load(path string) (*Fixture, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &Fixture{}
+	err = json.Unmarshal(b, f)
+	if err != nil {
+		return nil, fmt.Errorf("fixture %s: %w", path, err)
+	}
+
+	if f.MySQL == nil {
+		f.MySQL = &DB{}
+	}
+	if f.ClickHouse == nil {
+		f.ClickHouse = &ClickHouse{}
+	}
+
+	return f, nil
+}
+
+// filterLogScores returns the log scores for the server (and monitor
+// if not zero) with a timestamp after 'after' and no later than 'to';
+// zero times and limits don't filter.
+func filterLogScores(ls []ntpdb.LogScore, serverID, monitorID uint32, after, to time.Time, limit int, recentFirst bool) []ntpdb.LogScore {
+	rv := []ntpdb.LogScore{}
+	for _, l := range ls {
+		if l.ServerID != serverID {
+			continue
+		}
+		if monitorID > 0 && (!l.MonitorID.Valid || uint32(l.MonitorID.Int32) != monitorID) {
+			continue
+		}
+		if !after.IsZero() && !l.Ts.After(after) {
+			continue
+		}
+		if !to.IsZero() && l.Ts.After(to) {
+			continue
+		}
+		rv = append(rv, l)
+	}
+
+	sort.SliceStable(rv, func(i, j int) bool {
+		if recentFirst {
+			return rv[i].Ts.After(rv[j].Ts)
+		}
+		return rv[i].Ts.Before(rv[j].Ts)
+	})
+
+	if limit > 0 && len(rv) > limit {
+		rv = rv[:limit]
+	}
+
+	return rv
+}
+
+// like returns true if s matches the SQL LIKE pattern
+func like(s, pattern string) bool {
+	re := strings.NewReplacer("%", ".*", "_", ".").Replace(regexp.QuoteMeta(pattern))
+	return regexp.MustCompile("^" + re + "$").MatchString(s)
+}
@@ -0,0 +1,759 @@
+package fakedb
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.ntppool.org/data-api/ntpdb"
+)
+
+// ServerScore is a server_scores row joined with its monitor, as
+// returned by GetServerScores.
+type ServerScore struct {
+	ServerID uint32 `json:"server_id"`
+	ntpdb.GetServerScoresRow
+}
+
+// ServerVerification is a server_verifications row, as returned by
+// GetServerVerification.
+type ServerVerification struct {
+	ServerID uint32 `json:"server_id"`
+	ntpdb.GetServerVerificationRow
+}
+
+// ServerVerificationHistory is a server_verifications_history row, as
+// returned by GetServerVerificationHistory.
+type ServerVerificationHistory struct {
+	ServerID uint32 `json:"server_id"`
+	ntpdb.GetServerVerificationHistoryRow
+}
+
+// Account is the accounts columns used by the queries
+type Account struct {
+	ID            uint32 `json:"id"`
+	IDToken       string `json:"id_token"`
+	URLSlug       string `json:"url_slug"`
+	PublicProfile bool   `json:"public_profile"`
+
+	Name             string `json:"name"`
+	OrganizationName string `json:"organization_name"`
+	OrganizationURL  string `json:"organization_url"`
+}
+
+// ServerZone is a server_zones row
+type ServerZone struct {
+	ServerID uint32 `json:"server_id"`
+	ZoneID   uint32 `json:"zone_id"`
+}
+
+// ServerURL is a server_urls row
+type ServerURL struct {
+	ServerID uint32 `json:"server_id"`
+	URL      string `json:"url"`
+}
+
+// ServerMonitorReview is a servers_monitor_review row, as returned by
+// GetServerMonitorReview.
+type ServerMonitorReview struct {
+	ServerID uint32 `json:"server_id"`
+	ntpdb.GetServerMonitorReviewRow
+}
+
+// ScorerStatus is a scorer_status row
+type ScorerStatus struct {
+	ScorerID   uint32    `json:"scorer_id"`
+	LogScoreID uint64    `json:"log_score_id"`
+	ModifiedOn time.Time `json:"modified_on"`
+}
+
+// VendorZone is the vendor_zones columns used by the queries, with
+// the dns_roots origin
+type VendorZone struct {
+	ID         uint32                      `json:"id"`
+	ZoneName   string                      `json:"zone_name"`
+	Origin     string                      `json:"origin"`
+	Status     string                      `json:"status"`
+	ClientType ntpdb.VendorZonesClientType `json:"client_type"`
+}
+
+// DB is an in-memory ntpdb.DB
+type DB struct {
+	Accounts         []Account               `json:"accounts"`
+	DNSRoots         []ntpdb.DnsRoot         `json:"dns_roots"`
+	Servers          []ntpdb.Server          `json:"servers"`
+	VendorZones      []VendorZone            `json:"vendor_zones"`
+	Monitors         []ntpdb.Monitor         `json:"monitors"`
+	ServerScores     []ServerScore           `json:"server_scores"`
+	LogScores        []ntpdb.LogScore        `json:"log_scores"`
+	Zones            []ntpdb.Zone            `json:"zones"`
+	ZoneServerCounts []ntpdb.ZoneServerCount `json:"zone_server_counts"`
+
+	ServerZones                []ServerZone                `json:"server_zones"`
+	ServerURLs                 []ServerURL                 `json:"server_urls"`
+	ServerMonitorReviews       []ServerMonitorReview       `json:"servers_monitor_review"`
+	ServerVerifications        []ServerVerification        `json:"server_verifications"`
+	ServerVerificationsHistory []ServerVerificationHistory `json:"server_verifications_history"`
+
+	// ZoneStatsData is the zone server counts by date and zone name;
+	// GetZoneStatsData returns the rows for the latest date
+	ZoneStatsData []ntpdb.GetZoneStatsDataRow `json:"zone_stats_data"`
+
+	// ScorerStatus is the position of the scorers, for GetScorers
+	ScorerStatus []ScorerStatus `json:"scorer_status"`
+
+	// ZoneStatsV2 is the result of GetZoneStatsV2 by server IP
+	ZoneStatsV2 map[string][]ntpdb.GetZoneStatsV2Row `json:"zone_stats_v2"`
+
+	// ZoneNetspeedHistory is the netspeed of the server's zones by
+	// day, by server IP
+	ZoneNetspeedHistory map[string][]ntpdb.GetServerZoneNetspeedHistoryRow `json:"zone_netspeed_history"`
+
+	// Err is returned from every query and ping when set
+	Err error `json:"-"`
+
+	mu            sync.Mutex
+	archiveStatus []ntpdb.LogScoresArchiveStatus
+}
+
+var _ ntpdb.DB = (*DB)(nil)
+
+func (d *DB) Begin(ctx context.Context) (ntpdb.QuerierTx, error) {
+	return d, d.Err
+}
+
+func (d *DB) Commit(ctx context.Context) error {
+	return d.Err
+}
+
+func (d *DB) Rollback(ctx context.Context) error {
+	return d.Err
+}
+
+func (d *DB) PingContext(ctx context.Context) error {
+	return d.Err
+}
+
+func (d *DB) Stats() sql.DBStats {
+	return sql.DBStats{}
+}
+
+func (d *DB) ReplicaStatus() []ntpdb.ReplicaStatus {
+	return nil
+}
+
+func (d *DB) GetArchiveStatus(ctx context.Context, archiver string) (ntpdb.LogScoresArchiveStatus, error) {
+	if d.Err != nil {
+		return ntpdb.LogScoresArchiveStatus{}, d.Err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, s := range d.archiveStatus {
+		if s.Archiver == archiver {
+			return s, nil
+		}
+	}
+	return ntpdb.LogScoresArchiveStatus{}, sql.ErrNoRows
+}
+
+func (d *DB) InsertArchiveStatus(ctx context.Context, archiver string) error {
+	if d.Err != nil {
+		return d.Err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.archiveStatus = append(d.archiveStatus, ntpdb.LogScoresArchiveStatus{
+		ID:         uint32(len(d.archiveStatus) + 1),
+		Archiver:   archiver,
+		ModifiedOn: time.Now(),
+	})
+	return nil
+}
+
+func (d *DB) UpdateArchiveStatus(ctx context.Context, arg ntpdb.UpdateArchiveStatusParams) error {
+	if d.Err != nil {
+		return d.Err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for i, s := range d.archiveStatus {
+		if s.Archiver == arg.Archiver {
+			d.archiveStatus[i].LogScoreID = arg.LogScoreID
+			d.archiveStatus[i].ModifiedOn = time.Now()
+		}
+	}
+	return nil
+}
+
+func (d *DB) GetDNSRoots(ctx context.Context) ([]ntpdb.DnsRoot, error) {
+	if d.Err != nil {
+		return nil, d.Err
+	}
+	rv := slices.Clone(d.DNSRoots)
+	sort.Slice(rv, func(i, j int) bool { return rv[i].Origin < rv[j].Origin })
+	return rv, nil
+}
+
+func (d *DB) GetLatestLogScoreTs(ctx context.Context) (time.Time, error) {
+	if d.Err != nil {
+		return time.Time{}, d.Err
+	}
+	var latest *ntpdb.LogScore
+	for i, l := range d.LogScores {
+		if latest == nil || l.ID > latest.ID {
+			latest = &d.LogScores[i]
+		}
+	}
+	if latest == nil {
+		return time.Time{}, sql.ErrNoRows
+	}
+	return latest.Ts, nil
+}
+
+func (d *DB) GetLatestLogScoreID(ctx context.Context) (uint64, error) {
+	if d.Err != nil {
+		return 0, d.Err
+	}
+	if len(d.LogScores) == 0 {
+		return 0, sql.ErrNoRows
+	}
+	var id uint64
+	for _, l := range d.LogScores {
+		id = max(id, l.ID)
+	}
+	return id, nil
+}
+
+func (d *DB) GetLatestZoneCountsDate(ctx context.Context) (time.Time, error) {
+	if d.Err != nil {
+		return time.Time{}, d.Err
+	}
+	var latest time.Time
+	for _, zc := range d.ZoneServerCounts {
+		if zc.Date.After(latest) {
+			latest = zc.Date
+		}
+	}
+	for _, r := range d.ZoneStatsData {
+		if r.Date.After(latest) {
+			latest = r.Date
+		}
+	}
+	if latest.IsZero() {
+		return time.Time{}, sql.ErrNoRows
+	}
+	return latest, nil
+}
+
+func (d *DB) GetLogScoresAfterID(ctx context.Context, arg ntpdb.GetLogScoresAfterIDParams) ([]ntpdb.LogScore, error) {
+	if d.Err != nil {
+		return nil, d.Err
+	}
+	rv := []ntpdb.LogScore{}
+	for _, l := range d.LogScores {
+		if l.ID > arg.ID {
+			rv = append(rv, l)
+		}
+	}
+	sort.Slice(rv, func(i, j int) bool { return rv[i].ID < rv[j].ID })
+	if len(rv) > int(arg.Limit) {
+		rv = rv[:arg.Limit]
+	}
+	return rv, nil
+}
+
+func (d *DB) GetMonitorByNameAndIPVersion(ctx context.Context, arg ntpdb.GetMonitorByNameAndIPVersionParams) (ntpdb.Monitor, error) {
+	if d.Err != nil {
+		return ntpdb.Monitor{}, d.Err
+	}
+	var rv *ntpdb.Monitor
+	for i, m := range d.Monitors {
+		if !m.TlsName.Valid || !like(m.TlsName.String, arg.TlsName.String) {
+			continue
+		}
+		if m.IpVersion != arg.IpVersion || !m.IsCurrent.Bool || m.Status == "deleted" {
+			continue
+		}
+		if rv == nil || m.ID < rv.ID {
+			rv = &d.Monitors[i]
+		}
+	}
+	if rv == nil {
+		return ntpdb.Monitor{}, sql.ErrNoRows
+	}
+	return *rv, nil
+}
+
+func (d *DB) GetMonitorsByID(ctx context.Context, monitorids []uint32) ([]ntpdb.Monitor, error) {
+	if d.Err != nil {
+		return nil, d.Err
+	}
+	rv := []ntpdb.Monitor{}
+	for _, m := range d.Monitors {
+		if slices.Contains(monitorids, m.ID) {
+			rv = append(rv, m)
+		}
+	}
+	return rv, nil
+}
+
+// GetScorers returns the monitors of type score (not deleted) with
+// their position from ScorerStatus and the time of that log score.
+func (d *DB) GetScorers(ctx context.Context) ([]ntpdb.GetScorersRow, error) {
+	if d.Err != nil {
+		return nil, d.Err
+	}
+	rv := []ntpdb.GetScorersRow{}
+	for _, m := range d.Monitors {
+		if m.Type != ntpdb.MonitorsTypeScore || m.DeletedOn.Valid {
+			continue
+		}
+		r := ntpdb.GetScorersRow{ID: m.ID, Hostname: m.Hostname, Status: m.Status}
+		for _, ss := range d.ScorerStatus {
+			if ss.ScorerID == m.ID {
+				r.LogScoreID = sql.NullInt64{Int64: int64(ss.LogScoreID), Valid: true}
+				r.ModifiedOn = sql.NullTime{Time: ss.ModifiedOn, Valid: true}
+				for _, ls := range d.LogScores {
+					if ls.ID == ss.LogScoreID {
+						r.LogScoreTs = sql.NullTime{Time: ls.Ts, Valid: true}
+					}
+				}
+			}
+		}
+		rv = append(rv, r)
+	}
+	sort.Slice(rv, func(i, j int) bool {
+		if rv[i].Hostname != rv[j].Hostname {
+			return rv[i].Hostname < rv[j].Hostname
+		}
+		return rv[i].ID < rv[j].ID
+	})
+	return rv, nil
+}
+
+// GetPublicAccount returns the account if it has a public profile
+func (d *DB) GetPublicAccount(ctx context.Context, id uint32) (ntpdb.GetPublicAccountRow, error) {
+	if d.Err != nil {
+		return ntpdb.GetPublicAccountRow{}, d.Err
+	}
+	str := func(s string) sql.NullString {
+		return sql.NullString{String: s, Valid: len(s) > 0}
+	}
+	for _, a := range d.Accounts {
+		if a.ID != id || !a.PublicProfile {
+			continue
+		}
+		return ntpdb.GetPublicAccountRow{
+			IDToken:          str(a.IDToken),
+			Name:             str(a.Name),
+			OrganizationName: str(a.OrganizationName),
+			OrganizationUrl:  str(a.OrganizationURL),
+			UrlSlug:          str(a.URLSlug),
+		}, nil
+	}
+	return ntpdb.GetPublicAccountRow{}, sql.ErrNoRows
+}
+
+func (d *DB) GetServerByID(ctx context.Context, id uint32) (ntpdb.Server, error) {
+	if d.Err != nil {
+		return ntpdb.Server{}, d.Err
+	}
+	for _, s := range d.Servers {
+		if s.ID == id {
+			return s, nil
+		}
+	}
+	return ntpdb.Server{}, sql.ErrNoRows
+}
+
+func (d *DB) GetServerByIP(ctx context.Context, ip string) (ntpdb.Server, error) {
+	if d.Err != nil {
+		return ntpdb.Server{}, d.Err
+	}
+	for _, s := range d.Servers {
+		if s.Ip == ip {
+			return s, nil
+		}
+	}
+	return ntpdb.Server{}, sql.ErrNoRows
+}
+
+func (d *DB) GetServerMonitorReview(ctx context.Context, serverID uint32) (ntpdb.GetServerMonitorReviewRow, error) {
+	if d.Err != nil {
+		return ntpdb.GetServerMonitorReviewRow{}, d.Err
+	}
+	for _, r := range d.ServerMonitorReviews {
+		if r.ServerID == serverID {
+			return r.GetServerMonitorReviewRow, nil
+		}
+	}
+	return ntpdb.GetServerMonitorReviewRow{}, sql.ErrNoRows
+}
+
+func (d *DB) GetServerURLs(ctx context.Context, serverID uint32) ([]string, error) {
+	if d.Err != nil {
+		return nil, d.Err
+	}
+	rv := []string{}
+	for _, u := range d.ServerURLs {
+		if u.ServerID == serverID {
+			rv = append(rv, u.URL)
+		}
+	}
+	return rv, nil
+}
+
+func (d *DB) GetServerVerification(ctx context.Context, serverID uint32) (ntpdb.GetServerVerificationRow, error) {
+	if d.Err != nil {
+		return ntpdb.GetServerVerificationRow{}, d.Err
+	}
+	for _, v := range d.ServerVerifications {
+		if v.ServerID == serverID {
+			return v.GetServerVerificationRow, nil
+		}
+	}
+	return ntpdb.GetServerVerificationRow{}, sql.ErrNoRows
+}
+
+func (d *DB) GetServerVerificationHistory(ctx context.Context, serverID uint32) ([]ntpdb.GetServerVerificationHistoryRow, error) {
+	if d.Err != nil {
+		return nil, d.Err
+	}
+	rv := []ntpdb.GetServerVerificationHistoryRow{}
+	for _, v := range d.ServerVerificationsHistory {
+		if v.ServerID == serverID {
+			rv = append(rv, v.GetServerVerificationHistoryRow)
+		}
+	}
+	sort.Slice(rv, func(i, j int) bool {
+		if !rv[i].CreatedOn.Equal(rv[j].CreatedOn) {
+			return rv[i].CreatedOn.After(rv[j].CreatedOn)
+		}
+		return rv[i].ID > rv[j].ID
+	})
+	if len(rv) > 100 {
+		rv = rv[:100]
+	}
+	return rv, nil
+}
+
+func (d *DB) GetServersByAccount(ctx context.Context, account string) ([]ntpdb.Server, error) {
+	if d.Err != nil {
+		return nil, d.Err
+	}
+	var accountID uint32
+	for _, a := range d.Accounts {
+		if a.PublicProfile && (a.IDToken == account || a.URLSlug == account) {
+			accountID = a.ID
+		}
+	}
+	if accountID == 0 {
+		return []ntpdb.Server{}, nil
+	}
+	return d.activeServers(func(s ntpdb.Server) bool {
+		return s.AccountID.Valid && uint32(s.AccountID.Int32) == accountID
+	}), nil
+}
+
+func (d *DB) GetServersByHostname(ctx context.Context, hostname sql.NullString) ([]ntpdb.Server, error) {
+	if d.Err != nil {
+		return nil, d.Err
+	}
+	public := map[uint32]bool{}
+	for _, a := range d.Accounts {
+		if a.PublicProfile {
+			public[a.ID] = true
+		}
+	}
+	return d.activeServers(func(s ntpdb.Server) bool {
+		return s.Hostname.Valid && s.Hostname.String == hostname.String &&
+			s.AccountID.Valid && public[uint32(s.AccountID.Int32)]
+	}), nil
+}
+
+// activeServers returns the servers not deleted that match, ordered
+// by IP version and IP
+func (d *DB) activeServers(match func(ntpdb.Server) bool) []ntpdb.Server {
+	now := time.Now()
+	rv := []ntpdb.Server{}
+	for _, s := range d.Servers {
+		if s.DeletionOn.Valid && !s.DeletionOn.Time.After(now) {
+			continue
+		}
+		if match(s) {
+			rv = append(rv, s)
+		}
+	}
+	sort.SliceStable(rv, func(i, j int) bool {
+		if rv[i].IpVersion != rv[j].IpVersion {
+			return rv[i].IpVersion < rv[j].IpVersion
+		}
+		return rv[i].Ip < rv[j].Ip
+	})
+	return rv
+}
+
+func (d *DB) GetServerLogScoresByTime(ctx context.Context, arg ntpdb.GetServerLogScoresByTimeParams) ([]ntpdb.LogScore, error) {
+	if d.Err != nil {
+		return nil, d.Err
+	}
+	return filterLogScores(d.LogScores, arg.ServerID, uint32(arg.MonitorID.Int32),
+		arg.Since, arg.Until, int(arg.Limit), false), nil
+}
+
+func (d *DB) GetServerLogScoresByTimeDesc(ctx context.Context, arg ntpdb.GetServerLogScoresByTimeDescParams) ([]ntpdb.LogScore, error) {
+	if d.Err != nil {
+		return nil, d.Err
+	}
+	return filterLogScores(d.LogScores, arg.ServerID, uint32(arg.MonitorID.Int32),
+		arg.Since, arg.Until, int(arg.Limit), true), nil
+}
+
+func (d *DB) GetServerNetspeed(ctx context.Context, ip string) (uint32, error) {
+	s, err := d.GetServerByIP(ctx, ip)
+	if err != nil {
+		return 0, err
+	}
+	return s.Netspeed, nil
+}
+
+func (d *DB) GetServerScores(ctx context.Context, arg ntpdb.GetServerScoresParams) ([]ntpdb.GetServerScoresRow, error) {
+	if d.Err != nil {
+		return nil, d.Err
+	}
+	rv := []ntpdb.GetServerScoresRow{}
+	for _, ss := range d.ServerScores {
+		if ss.ServerID == arg.ServerID && slices.Contains(arg.MonitorIDs, ss.ID) {
+			rv = append(rv, ss.GetServerScoresRow)
+		}
+	}
+	return rv, nil
+}
+
+func (d *DB) GetServerZoneNetspeedHistory(ctx context.Context, arg ntpdb.GetServerZoneNetspeedHistoryParams) ([]ntpdb.GetServerZoneNetspeedHistoryRow, error) {
+	if d.Err != nil {
+		return nil, d.Err
+	}
+	rv := []ntpdb.GetServerZoneNetspeedHistoryRow{}
+	for _, r := range d.ZoneNetspeedHistory[arg.ServerIP] {
+		if r.Date.Before(arg.FromDate) || r.Date.After(arg.ToDate) {
+			continue
+		}
+		rv = append(rv, r)
+	}
+	return rv, nil
+}
+
+func (d *DB) GetServerZones(ctx context.Context, serverID uint32) ([]string, error) {
+	if d.Err != nil {
+		return nil, d.Err
+	}
+	rv := []string{}
+	for _, sz := range d.ServerZones {
+		if sz.ServerID != serverID {
+			continue
+		}
+		for _, z := range d.Zones {
+			if z.ID == sz.ZoneID {
+				rv = append(rv, z.Name)
+			}
+		}
+	}
+	sort.Strings(rv)
+	return rv, nil
+}
+
+func (d *DB) GetZoneByName(ctx context.Context, name string) (ntpdb.Zone, error) {
+	if d.Err != nil {
+		return ntpdb.Zone{}, d.Err
+	}
+	for _, z := range d.Zones {
+		if z.Name == name {
+			return z, nil
+		}
+	}
+	return ntpdb.Zone{}, sql.ErrNoRows
+}
+
+func (d *DB) GetZoneCounts(ctx context.Context, zoneID uint32) ([]ntpdb.ZoneServerCount, error) {
+	if d.Err != nil {
+		return nil, d.Err
+	}
+	rv := []ntpdb.ZoneServerCount{}
+	for _, zc := range d.ZoneServerCounts {
+		if zc.ZoneID == zoneID {
+			rv = append(rv, zc)
+		}
+	}
+	sort.SliceStable(rv, func(i, j int) bool { return rv[i].Date.Before(rv[j].Date) })
+	return rv, nil
+}
+
+func (d *DB) GetZoneStatsData(ctx context.Context) ([]ntpdb.GetZoneStatsDataRow, error) {
+	if d.Err != nil {
+		return nil, d.Err
+	}
+	var latest time.Time
+	for _, r := range d.ZoneStatsData {
+		if r.Date.After(latest) {
+			latest = r.Date
+		}
+	}
+	rv := []ntpdb.GetZoneStatsDataRow{}
+	for _, r := range d.ZoneStatsData {
+		if r.Date.Equal(latest) {
+			rv = append(rv, r)
+		}
+	}
+	return rv, nil
+}
+
+func (d *DB) GetZoneStatsHistory(ctx context.Context, arg ntpdb.GetZoneStatsHistoryParams) ([]ntpdb.GetZoneStatsHistoryRow, error) {
+	if d.Err != nil {
+		return nil, d.Err
+	}
+	rv := []ntpdb.GetZoneStatsHistoryRow{}
+	for _, r := range d.ZoneStatsData {
+		if r.Date.Before(arg.FromDate) || r.Date.After(arg.ToDate) {
+			continue
+		}
+		rv = append(rv, ntpdb.GetZoneStatsHistoryRow(r))
+	}
+	sort.SliceStable(rv, func(i, j int) bool {
+		if !rv[i].Date.Equal(rv[j].Date) {
+			return rv[i].Date.Before(rv[j].Date)
+		}
+		return rv[i].Name < rv[j].Name
+	})
+	return rv, nil
+}
+
+func (d *DB) GetZoneStatsV2(ctx context.Context, ip string) ([]ntpdb.GetZoneStatsV2Row, error) {
+	if d.Err != nil {
+		return nil, d.Err
+	}
+	return d.ZoneStatsV2[ip], nil
+}
+
+func (d *DB) GetVendorZone(ctx context.Context, arg ntpdb.GetVendorZoneParams) (ntpdb.GetVendorZoneRow, error) {
+	if d.Err != nil {
+		return ntpdb.GetVendorZoneRow{}, d.Err
+	}
+	for _, vz := range d.VendorZones {
+		if vz.ZoneName == arg.ZoneName && vz.Origin == arg.Origin && vz.Status == "Approved" {
+			return ntpdb.GetVendorZoneRow{
+				ID:         vz.ID,
+				ZoneName:   vz.ZoneName,
+				ClientType: vz.ClientType,
+				Origin:     vz.Origin,
+			}, nil
+		}
+	}
+	return ntpdb.GetVendorZoneRow{}, sql.ErrNoRows
+}
+
+// serverSearch is the filters of the SearchServers queries
+// match returns true if the server is listed on the public site and
+// matches the filters; the hostname is a LIKE pattern, only
+// %substring% patterns are supported.
+func (d *DB) match(arg ntpdb.SearchServersParams, s ntpdb.Server) bool {
+	if s.InServerList != 1 || s.DeletionOn.Valid || !s.AccountID.Valid {
+		return false
+	}
+	var account *Account
+	for i, a := range d.Accounts {
+		if a.ID == uint32(s.AccountID.Int32) && a.PublicProfile {
+			account = &d.Accounts[i]
+		}
+	}
+	if account == nil {
+		return false
+	}
+	if arg.Account.Valid && account.URLSlug != arg.Account.String {
+		return false
+	}
+	if arg.Zone.Valid {
+		found := false
+		for _, sz := range d.ServerZones {
+			if sz.ServerID != s.ID {
+				continue
+			}
+			for _, z := range d.Zones {
+				if z.ID == sz.ZoneID && z.Name == arg.Zone.String {
+					found = true
+				}
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if arg.IpVersion.Valid && s.IpVersion != arg.IpVersion.ServersIpVersion {
+		return false
+	}
+	if arg.MinScore.Valid && s.ScoreRaw < arg.MinScore.Float64 {
+		return false
+	}
+	if arg.MinNetspeed.Valid && s.Netspeed < uint32(arg.MinNetspeed.Int32) {
+		return false
+	}
+	if arg.MaxNetspeed.Valid && s.Netspeed > uint32(arg.MaxNetspeed.Int32) {
+		return false
+	}
+	if arg.Hostname.Valid {
+		sub := strings.Trim(arg.Hostname.String, "%")
+		sub = strings.NewReplacer(`\%`, "%", `\_`, "_", `\\`, `\`).Replace(sub)
+		if !s.Hostname.Valid || !strings.Contains(strings.ToLower(s.Hostname.String), strings.ToLower(sub)) {
+			return false
+		}
+	}
+	return true
+}
+
+// sortValue is the value of the server for the search sort ('id',
+// 'score' or 'netspeed'); it's 0 for the 'id' sort.
+func sortValue(sort interface{}, s ntpdb.Server) float64 {
+	switch sort {
+	case "score":
+		return s.ScoreRaw
+	case "netspeed":
+		return float64(s.Netspeed)
+	}
+	return 0
+}
+
+func (d *DB) SearchServers(ctx context.Context, arg ntpdb.SearchServersParams) ([]ntpdb.Server, error) {
+	if d.Err != nil {
+		return nil, d.Err
+	}
+
+	rv := []ntpdb.Server{}
+	for _, s := range d.Servers {
+		if !d.match(arg, s) {
+			continue
+		}
+		if after, ok := arg.AfterValue.(float64); ok {
+			v := sortValue(arg.Sort, s)
+			if v > after || (v == after && s.ID <= arg.AfterID) {
+				continue
+			}
+		}
+		rv = append(rv, s)
+	}
+
+	sort.Slice(rv, func(i, j int) bool {
+		vi, vj := sortValue(arg.Sort, rv[i]), sortValue(arg.Sort, rv[j])
+		if vi != vj {
+			return vi > vj
+		}
+		return rv[i].ID < rv[j].ID
+	})
+	if len(rv) > int(arg.Limit) {
+		rv = rv[:arg.Limit]
+	}
+	return rv, nil
+}
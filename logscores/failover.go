@@ -0,0 +1,165 @@
+package logscores
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"go.ntppool.org/common/logger"
+	"go.ntppool.org/common/tracing"
+)
+
+// Failover reads history from the primary store and switches to
+// the fallback store for recent history while the primary is
+// unavailable.
+type Failover struct {
+	primary  Store
+	fallback Store
+
+	// RetryAfter is how long the primary is skipped after a failure
+	// (unless the health check finds it working again before then)
+	RetryAfter time.Duration
+
+	// FallbackWindow is how far back the fallback store has log scores
+	FallbackWindow time.Duration
+
+	mu        sync.RWMutex
+	downUntil time.Time
+}
+
+func NewFailover(primary, fallback Store) *Failover {
+	return &Failover{
+		primary:        primary,
+		fallback:       fallback,
+		RetryAfter:     30 * time.Second,
+		FallbackWindow: 7 * 24 * time.Hour,
+	}
+}
+
+func (f *Failover) Name() string {
+	return f.primary.Name()
+}
+
+// Healthy returns false if the primary store recently failed
+func (f *Failover) Healthy() bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return time.Now().After(f.downUntil)
+}
+
+func (f *Failover) setHealthy(healthy bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if healthy {
+		f.downUntil = time.Time{}
+	} else {
+		f.downUntil = time.Now().Add(f.RetryAfter)
+	}
+}
+
+func (f *Failover) History(ctx context.Context, q Query) (*LogScoreHistory, error) {
+	log := logger.FromContext(ctx)
+	ctx, span := tracing.Tracer().Start(ctx, "logscores.Failover")
+	defer span.End()
+
+	canFallback := f.canFallback(q)
+
+	if canFallback && !f.Healthy() {
+		span.AddEvent("primary unavailable", trace.WithAttributes(attribute.String("fallback", f.fallback.Name())))
+		return f.fallback.History(ctx, q)
+	}
+
+	history, err := f.primary.History(ctx, q)
+	if err == nil || !f.storeFailed(ctx, err) {
+		return history, err
+	}
+
+	f.setHealthy(false)
+
+	if !canFallback {
+		return nil, err
+	}
+
+	log.WarnContext(ctx, "history store failed, using fallback",
+		"primary", f.primary.Name(), "fallback", f.fallback.Name(), "err", err)
+	span.AddEvent("primary failed", trace.WithAttributes(attribute.String("fallback", f.fallback.Name())))
+
+	return f.fallback.History(ctx, q)
+}
+
+// canFallback returns true if the query only needs log scores the
+// fallback store still has
+func (f *Failover) canFallback(q Query) bool {
+	if q.FullHistory {
+		return false
+	}
+	r := q.resolve(time.Now(), DefaultWindow)
+	return r.after.After(time.Now().Add(-f.FallbackWindow))
+}
+
+// storeFailed returns true if err indicates that the store is
+// having trouble rather than the request being invalid or cancelled
+func (f *Failover) storeFailed(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var httpError *echo.HTTPError
+	if errors.As(err, &httpError) && httpError.Code < 500 {
+		return false
+	}
+	return true
+}
+
+func (f *Failover) Ping(ctx context.Context) error {
+	err := f.primary.Ping(ctx)
+	if err == nil {
+		return nil
+	}
+	return f.fallback.Ping(ctx)
+}
+
+// Run checks the primary store at the interval until the context
+// is cancelled, so it's skipped or used again without waiting for
+// requests to fail.
+func (f *Failover) Run(ctx context.Context, interval time.Duration) error {
+	log := logger.FromContext(ctx)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		pingCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		err := f.primary.Ping(pingCtx)
+		cancel()
+
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		wasHealthy := f.Healthy()
+
+		if err != nil {
+			if wasHealthy {
+				log.WarnContext(ctx, "history store unavailable", "store", f.primary.Name(), "err", err)
+			}
+			f.setHealthy(false)
+			continue
+		}
+
+		if !wasHealthy {
+			log.InfoContext(ctx, "history store available again", "store", f.primary.Name())
+		}
+		f.setHealthy(true)
+	}
+}
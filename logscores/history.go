@@ -2,98 +2,109 @@ package logscores
 
 import (
 	"context"
-	"database/sql"
 	"time"
 
-	"go.ntppool.org/common/logger"
-	"go.ntppool.org/common/tracing"
 	"go.ntppool.org/data-api/chdb"
 	"go.ntppool.org/data-api/ntpdb"
-	"go.opentelemetry.io/otel/attribute"
-	"go.opentelemetry.io/otel/trace"
 )
 
+// DefaultWindow is how far back a query without a start time goes
+const DefaultWindow = 4 * 24 * time.Hour
+
 type LogScoreHistory struct {
 	LogScores []ntpdb.LogScore
 	Monitors  map[int]string
 	// MonitorIDs []uint32
+
+	// Source is the name of the store(s) the data came from
+	Source string
 }
 
-func GetHistoryClickHouse(ctx context.Context, ch *chdb.ClickHouse, db *sql.DB, serverID, monitorID uint32, since time.Time, count int, fullHistory bool) (*LogScoreHistory, error) {
-	log := logger.FromContext(ctx)
-	ctx, span := tracing.Tracer().Start(ctx, "logscores.GetHistoryClickHouse",
-		trace.WithAttributes(
-			attribute.Int("server", int(serverID)),
-			attribute.Int("monitor", int(monitorID)),
-			attribute.Bool("full_history", fullHistory),
-		),
-	)
-	defer span.End()
+type Order uint8
 
-	log.DebugContext(ctx, "GetHistoryCH", "server", serverID, "monitor", monitorID, "since", since, "count", count, "full_history", fullHistory)
+const (
+	// OrderDefault is ascending if the query has a start time and
+	// most recent first otherwise
+	OrderDefault Order = iota
+	OrderAscending
+	OrderDescending
+)
 
-	ls, err := ch.Logscores(ctx, int(serverID), int(monitorID), since, count, fullHistory)
+// Query selects the log scores for a server
+type Query struct {
+	ServerID uint32
+	// MonitorID zero returns the log scores from all monitors
+	MonitorID uint32
 
-	if err != nil {
-		log.ErrorContext(ctx, "clickhouse logscores", "err", err)
-		return nil, err
-	}
+	// Since returns log scores after this time
+	Since time.Time
 
-	q := ntpdb.NewWrappedQuerier(ntpdb.New(db))
+	// From and To return log scores in the time range, including
+	// the start and end. From takes precedence over Since.
+	From time.Time
+	To   time.Time
 
-	monitors, err := getMonitorNames(ctx, ls, q)
-	if err != nil {
-		return nil, err
-	}
+	// Limit zero returns all log scores in the time range
+	Limit int
+	Order Order
 
-	return &LogScoreHistory{
-		LogScores: ls,
-		Monitors:  monitors,
-	}, nil
+	// FullHistory ignores the time range and limit
+	FullHistory bool
 }
 
-func GetHistoryMySQL(ctx context.Context, db *sql.DB, serverID, monitorID uint32, since time.Time, count int) (*LogScoreHistory, error) {
-	log := logger.FromContext(ctx)
-	ctx, span := tracing.Tracer().Start(ctx, "logscores.GetHistoryMySQL")
-	defer span.End()
-
-	span.SetAttributes(
-		attribute.Int("server", int(serverID)),
-		attribute.Int("monitor", int(monitorID)),
-	)
-
-	log.Debug("GetHistoryMySQL", "server", serverID, "monitor", monitorID, "since", since, "count", count)
-
-	q := ntpdb.NewWrappedQuerier(ntpdb.New(db))
-
-	var ls []ntpdb.LogScore
-	var err error
-	if monitorID > 0 {
-		ls, err = q.GetServerLogScoresByMonitorID(ctx, ntpdb.GetServerLogScoresByMonitorIDParams{
-			ServerID:  serverID,
-			MonitorID: sql.NullInt32{Int32: int32(monitorID), Valid: true},
-			Limit:     int32(count),
-		})
-	} else {
-		ls, err = q.GetServerLogScores(ctx, ntpdb.GetServerLogScoresParams{
-			ServerID: serverID,
-			Limit:    int32(count),
-		})
+// timeRange is the resolved time range and order of a query
+type timeRange struct {
+	after       time.Time // exclusive; zero for no start
+	to          time.Time // inclusive; zero for no end
+	limit       int
+	recentFirst bool
+}
+
+// resolve returns the time range for the query. Queries without a
+// start time get the window, if set.
+func (q Query) resolve(now time.Time, window time.Duration) timeRange {
+	r := timeRange{
+		after: q.Since,
+		to:    q.To,
+		limit: q.Limit,
 	}
-	if err != nil {
-		return nil, err
+
+	if !q.From.IsZero() {
+		// log score timestamps have second resolution
+		r.after = q.From.Add(-time.Second)
 	}
 
-	monitors, err := getMonitorNames(ctx, ls, q)
-	if err != nil {
-		return nil, err
+	if r.after.IsZero() && !q.FullHistory {
+		r.recentFirst = true
+		if window > 0 {
+			r.after = now.Add(-window)
+		}
+	}
+
+	if q.FullHistory {
+		r = timeRange{}
+	}
+
+	switch q.Order {
+	case OrderAscending:
+		r.recentFirst = false
+	case OrderDescending:
+		r.recentFirst = true
 	}
 
-	return &LogScoreHistory{
-		LogScores: ls,
-		Monitors:  monitors,
-		// MonitorIDs: monitorIDs,
-	}, nil
+	return r
+}
+
+// chdb returns the ClickHouse query for the time range
+func (r timeRange) chdb(q Query) chdb.LogscoresQuery {
+	return chdb.LogscoresQuery{
+		ServerID:    int(q.ServerID),
+		MonitorID:   int(q.MonitorID),
+		After:       r.after,
+		To:          r.to,
+		Limit:       r.limit,
+		RecentFirst: r.recentFirst,
+	}
 }
 
 func getMonitorNames(ctx context.Context, ls []ntpdb.LogScore, q ntpdb.QuerierTx) (map[int]string, error) {
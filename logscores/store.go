@@ -0,0 +1,259 @@
+package logscores
+
+import (
+	"context"
+	"database/sql"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"go.ntppool.org/common/logger"
+	"go.ntppool.org/common/tracing"
+	"go.ntppool.org/data-api/chdb"
+	"go.ntppool.org/data-api/ntpdb"
+)
+
+// Store is a source of log score history
+type Store interface {
+	// Name is reported to clients as the source of the data
+	Name() string
+	History(ctx context.Context, q Query) (*LogScoreHistory, error)
+	Ping(ctx context.Context) error
+}
+
+func startSpan(ctx context.Context, name string, q Query) (context.Context, trace.Span) {
+	return tracing.Tracer().Start(ctx, name,
+		trace.WithAttributes(
+			attribute.Int("server", int(q.ServerID)),
+			attribute.Int("monitor", int(q.MonitorID)),
+			attribute.Bool("full_history", q.FullHistory),
+		),
+	)
+}
+
+func newHistory(ctx context.Context, q ntpdb.QuerierTx, ls []ntpdb.LogScore, source string) (*LogScoreHistory, error) {
+	monitors, err := getMonitorNames(ctx, ls, q)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LogScoreHistory{
+		LogScores: ls,
+		Monitors:  monitors,
+		Source:    source,
+	}, nil
+}
+
+type clickHouseStore struct {
+	ch chdb.Querier
+	db ntpdb.QuerierTx
+}
+
+// NewClickHouseStore returns a Store reading from ClickHouse and, for
+// log scores older than the ClickHouse retention, from the archive if
+// configured. Monitor names are read from MySQL.
+func NewClickHouseStore(ch chdb.Querier, db ntpdb.QuerierTx) Store {
+	return &clickHouseStore{ch: ch, db: db}
+}
+
+func (s *clickHouseStore) Name() string {
+	return "clickhouse"
+}
+
+func (s *clickHouseStore) History(ctx context.Context, q Query) (*LogScoreHistory, error) {
+	log := logger.FromContext(ctx)
+	ctx, span := startSpan(ctx, "logscores.ClickHouse", q)
+	defer span.End()
+
+	r := q.resolve(time.Now(), DefaultWindow)
+	cutoff := s.ch.ArchiveCutoff()
+
+	log.DebugContext(ctx, "clickhouse history", "query", q, "after", r.after, "to", r.to)
+
+	source := "clickhouse"
+	var ls []ntpdb.LogScore
+	var err error
+
+	switch {
+	case !s.ch.ArchiveEnabled() || (!r.after.IsZero() && !r.after.Before(cutoff)):
+		ls, err = s.ch.Logscores(ctx, r.chdb(q))
+
+	case !r.to.IsZero() && !r.to.After(cutoff):
+		source = "archive"
+		ls, err = s.ch.ArchiveLogscores(ctx, r.chdb(q))
+
+	default:
+		source = "archive,clickhouse"
+		span.AddEvent("reading archive")
+
+		archiveQuery := r.chdb(q)
+		archiveQuery.To = cutoff
+		chQuery := r.chdb(q)
+		chQuery.After = cutoff
+
+		archive := func(ctx context.Context, limit int) ([]ntpdb.LogScore, error) {
+			archiveQuery.Limit = limit
+			return s.ch.ArchiveLogscores(ctx, archiveQuery)
+		}
+		clickhouse := func(ctx context.Context, limit int) ([]ntpdb.LogScore, error) {
+			chQuery.Limit = limit
+			return s.ch.Logscores(ctx, chQuery)
+		}
+
+		if r.recentFirst {
+			ls, err = stitch(ctx, r.limit, clickhouse, archive)
+		} else {
+			ls, err = stitch(ctx, r.limit, archive, clickhouse)
+		}
+	}
+	if err != nil {
+		log.ErrorContext(ctx, "clickhouse logscores", "source", source, "err", err)
+		return nil, err
+	}
+
+	return newHistory(ctx, s.db, ls, source)
+}
+
+// stitch concatenates the log scores from each part in order until
+// the limit is reached.
+func stitch(ctx context.Context, limit int, parts ...func(ctx context.Context, limit int) ([]ntpdb.LogScore, error)) ([]ntpdb.LogScore, error) {
+	rv := []ntpdb.LogScore{}
+
+	for _, part := range parts {
+		partLimit := 0
+		if limit > 0 {
+			partLimit = limit - len(rv)
+			if partLimit <= 0 {
+				break
+			}
+		}
+
+		ls, err := part(ctx, partLimit)
+		if err != nil {
+			return nil, err
+		}
+		rv = append(rv, ls...)
+	}
+
+	return rv, nil
+}
+
+func (s *clickHouseStore) Ping(ctx context.Context) error {
+	return s.ch.PingScores(ctx)
+}
+
+type archiveStore struct {
+	ch chdb.Querier
+	db ntpdb.QuerierTx
+}
+
+// NewArchiveStore returns a Store reading only from the offline
+// log_scores archive.
+func NewArchiveStore(ch chdb.Querier, db ntpdb.QuerierTx) Store {
+	return &archiveStore{ch: ch, db: db}
+}
+
+func (s *archiveStore) Name() string {
+	return "archive"
+}
+
+func (s *archiveStore) History(ctx context.Context, q Query) (*LogScoreHistory, error) {
+	log := logger.FromContext(ctx)
+	ctx, span := startSpan(ctx, "logscores.Archive", q)
+	defer span.End()
+
+	if !s.ch.ArchiveEnabled() {
+		return nil, echo.NewHTTPError(http.StatusNotFound, "archive not available")
+	}
+
+	// the archive is mostly queried for old data, so don't limit
+	// queries without a start time to the recent window
+	r := q.resolve(time.Now(), 0)
+	cutoff := s.ch.ArchiveCutoff()
+
+	ls := []ntpdb.LogScore{}
+	if r.after.Before(cutoff) {
+		if r.to.IsZero() || r.to.After(cutoff) {
+			r.to = cutoff
+		}
+
+		var err error
+		ls, err = s.ch.ArchiveLogscores(ctx, r.chdb(q))
+		if err != nil {
+			log.ErrorContext(ctx, "archive logscores", "err", err)
+			return nil, err
+		}
+	}
+
+	return newHistory(ctx, s.db, ls, "archive")
+}
+
+func (s *archiveStore) Ping(ctx context.Context) error {
+	return s.ch.PingScores(ctx)
+}
+
+type mysqlStore struct {
+	db ntpdb.DB
+}
+
+// NewMySQLStore returns a Store reading the recent log scores that
+// are still in MySQL.
+func NewMySQLStore(db ntpdb.DB) Store {
+	return &mysqlStore{db: db}
+}
+
+func (s *mysqlStore) Name() string {
+	return "mysql"
+}
+
+func (s *mysqlStore) History(ctx context.Context, q Query) (*LogScoreHistory, error) {
+	log := logger.FromContext(ctx)
+	ctx, span := startSpan(ctx, "logscores.MySQL", q)
+	defer span.End()
+
+	now := time.Now()
+	r := q.resolve(now, DefaultWindow)
+
+	log.DebugContext(ctx, "mysql history", "query", q, "after", r.after, "to", r.to)
+
+	params := ntpdb.GetServerLogScoresByTimeParams{
+		ServerID: q.ServerID,
+		Since:    r.after,
+		Until:    r.to,
+		Limit:    math.MaxInt32,
+	}
+	if q.MonitorID > 0 {
+		params.MonitorID = sql.NullInt32{Int32: int32(q.MonitorID), Valid: true}
+	}
+	if params.Since.IsZero() {
+		params.Since = time.Unix(0, 0)
+	}
+	if params.Until.IsZero() {
+		// allow for some clock skew between the monitors and us
+		params.Until = now.Add(24 * time.Hour)
+	}
+	if r.limit > 0 && r.limit < math.MaxInt32 {
+		params.Limit = int32(r.limit)
+	}
+
+	var ls []ntpdb.LogScore
+	var err error
+	if r.recentFirst {
+		ls, err = s.db.GetServerLogScoresByTimeDesc(ctx, ntpdb.GetServerLogScoresByTimeDescParams(params))
+	} else {
+		ls, err = s.db.GetServerLogScoresByTime(ctx, params)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return newHistory(ctx, s.db, ls, "mysql")
+}
+
+func (s *mysqlStore) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
@@ -11,6 +11,8 @@ import (
 	"github.com/go-sql-driver/mysql"
 	"go.ntppool.org/common/logger"
 	"gopkg.in/yaml.v3"
+
+	"go.ntppool.org/data-api/envconfig"
 )
 
 type Config struct {
@@ -21,12 +23,38 @@ type DBConfig struct {
 	DSN  string `default:"" flag:"dsn" usage:"Database DSN"`
 	User string `default:"" flag:"user"`
 	Pass string `default:"" flag:"pass"`
+
+	// Replicas are read replicas of the database; the queries are
+	// sent to them first (see NewReplicatedDB). MaxReplicaLag is the
+	// replication delay at which a replica isn't used (default 30s).
+	Replicas      []DBConfig    `yaml:"replicas"`
+	MaxReplicaLag time.Duration `yaml:"max_replica_lag"`
+}
+
+// applyEnv overrides the configuration with the DATA_API_MYSQL_DSN,
+// _USER and _PASSWORD environment variables (or their _FILE variants).
+func (cfg *DBConfig) applyEnv() error {
+	return envconfig.Override(map[string]*string{
+		"MYSQL_DSN":      &cfg.DSN,
+		"MYSQL_USER":     &cfg.User,
+		"MYSQL_PASSWORD": &cfg.Pass,
+	})
+}
+
+// configFlagPrefix is the prefix of the command line flags for the
+// MySQL settings, for example --database.dsn
+const configFlagPrefix = "database."
+
+// ConfigFlags are the command line flags for the MySQL connection
+// settings; they override the configuration file and the environment.
+func ConfigFlags() []envconfig.Flag {
+	return envconfig.Flags(configFlagPrefix, &DBConfig{})
 }
 
 func OpenDB(ctx context.Context, configFile string) (*sql.DB, error) {
 	log := logger.FromContext(ctx)
 
-	dbconn := sql.OpenDB(Driver{CreateConnectorFunc: createConnector(ctx, configFile)})
+	dbconn := sql.OpenDB(Driver{CreateConnectorFunc: createConnector(ctx, configFile, -1)})
 
 	dbconn.SetConnMaxLifetime(time.Minute * 3)
 	dbconn.SetMaxOpenConns(8)
@@ -41,45 +69,157 @@ func OpenDB(ctx context.Context, configFile string) (*sql.DB, error) {
 	return dbconn, nil
 }
 
-func createConnector(ctx context.Context, configFile string) CreateConnectorFunc {
+// loadConfig reads the configuration file and applies the
+// environment overrides.
+func loadConfig(ctx context.Context, configFile string) (*Config, error) {
 	log := logger.FromContext(ctx)
-	return func() (driver.Connector, error) {
-		log.DebugContext(ctx, "opening db config file", "filename", configFile)
+	log.DebugContext(ctx, "opening db config file", "filename", configFile)
 
-		dbFile, err := os.Open(configFile)
-		if err != nil {
-			return nil, err
-		}
+	dbFile, err := os.Open(configFile)
+	if err != nil {
+		return nil, err
+	}
+	defer dbFile.Close()
 
-		dec := yaml.NewDecoder(dbFile)
+	dec := yaml.NewDecoder(dbFile)
 
-		cfg := Config{}
+	cfg := Config{}
 
-		err = dec.Decode(&cfg)
-		if err != nil {
-			return nil, err
-		}
+	err = dec.Decode(&cfg)
+	if err != nil {
+		return nil, err
+	}
 
-		// log.Printf("db cfg: %+v", cfg)
+	err = cfg.MySQL.applyEnv()
+	if err != nil {
+		return nil, err
+	}
+	envconfig.ApplyFlags(configFlagPrefix, &cfg.MySQL)
+
+	// log.Printf("db cfg: %+v", cfg)
+
+	return &cfg, nil
+}
 
-		dsn := cfg.MySQL.DSN
-		if len(dsn) == 0 {
-			return nil, fmt.Errorf("--database.dsn flag or DATABASE_DSN environment variable required")
+// mysqlConfig returns the driver configuration for the primary
+// database (replica -1) or for one of the replicas. The replicas
+// use the user and password for the primary if they don't have
+// their own.
+func (cfg *Config) mysqlConfig(replica int) (*mysql.Config, error) {
+	dbCfg := cfg.MySQL
+	if replica >= 0 {
+		if replica >= len(cfg.MySQL.Replicas) {
+			return nil, fmt.Errorf("mysql replica %d not configured", replica)
 		}
+		r := cfg.MySQL.Replicas[replica]
+		if len(r.User) == 0 {
+			r.User = dbCfg.User
+		}
+		if len(r.Pass) == 0 {
+			r.Pass = dbCfg.Pass
+		}
+		dbCfg = r
+	}
 
-		dbcfg, err := mysql.ParseDSN(dsn)
-		if err != nil {
-			return nil, err
+	dsn := dbCfg.DSN
+	if len(dsn) == 0 {
+		if replica >= 0 {
+			return nil, fmt.Errorf("dsn required for mysql replica %d", replica)
 		}
+		return nil, fmt.Errorf("mysql dsn in the configuration file, --database.dsn flag or DATA_API_MYSQL_DSN environment variable required")
+	}
 
-		if user := cfg.MySQL.User; len(user) > 0 {
-			dbcfg.User = user
+	dbcfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	if user := dbCfg.User; len(user) > 0 {
+		dbcfg.User = user
+	}
+
+	if pass := dbCfg.Pass; len(pass) > 0 {
+		dbcfg.Passwd = pass
+	}
+
+	return dbcfg, nil
+}
+
+// createConnector returns a function reading the configuration file
+// for each new connection to the primary database (replica -1) or to
+// one of the replicas, so changed credentials are used without a
+// restart.
+func createConnector(ctx context.Context, configFile string, replica int) CreateConnectorFunc {
+	return func() (driver.Connector, error) {
+		cfg, err := loadConfig(ctx, configFile)
+		if err != nil {
+			return nil, err
 		}
 
-		if pass := cfg.MySQL.Pass; len(pass) > 0 {
-			dbcfg.Passwd = pass
+		dbcfg, err := cfg.mysqlConfig(replica)
+		if err != nil {
+			return nil, err
 		}
 
 		return mysql.NewConnector(dbcfg)
 	}
 }
+
+// DB is the MySQL database used by the API server: the queries
+// and the connection pool checks in the health handler.
+type DB interface {
+	QuerierTx
+
+	PingContext(ctx context.Context) error
+	Stats() sql.DBStats
+
+	// ReplicaStatus is the state and connection pool of each of
+	// the read replicas
+	ReplicaStatus() []ReplicaStatus
+}
+
+type sqlDB struct {
+	QuerierTx
+	db       *sql.DB
+	replicas *Replicas
+}
+
+// NewDB returns a DB running the queries (with tracing) on db
+func NewDB(db *sql.DB) DB {
+	return &sqlDB{
+		QuerierTx: NewWrappedQuerier(New(db)),
+		db:        db,
+	}
+}
+
+// PingContext pings the primary database and the replicas. Replicas
+// that don't respond stop getting queries (see Replicas.Ping) and are
+// in ReplicaStatus; only the primary failing is an error, as the
+// queries go to it if there are no replicas.
+func (d *sqlDB) PingContext(ctx context.Context) error {
+	if d.replicas != nil {
+		// the errors are in the replica status
+		_ = d.replicas.Ping(ctx)
+	}
+	return d.db.PingContext(ctx)
+}
+
+// CheckReplicas checks the replication status of the read replicas
+// (if any) every interval until the context is done.
+func (d *sqlDB) CheckReplicas(ctx context.Context, interval time.Duration) error {
+	if d.replicas == nil {
+		return nil
+	}
+	return d.replicas.CheckReplicas(ctx, interval)
+}
+
+func (d *sqlDB) Stats() sql.DBStats {
+	return d.db.Stats()
+}
+
+func (d *sqlDB) ReplicaStatus() []ReplicaStatus {
+	if d.replicas == nil {
+		return nil
+	}
+	return d.replicas.Status()
+}
@@ -0,0 +1,79 @@
+package ntpdb
+
+import (
+	"database/sql"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// statsCollector reports the connection pool stats of the primary
+// database and the replicas and the replication state of the
+// replicas when the metrics are collected.
+type statsCollector struct {
+	db DB
+
+	openConns    *prometheus.Desc
+	inUse        *prometheus.Desc
+	idle         *prometheus.Desc
+	waitCount    *prometheus.Desc
+	waitDuration *prometheus.Desc
+
+	replicaHealthy *prometheus.Desc
+	replicaLag     *prometheus.Desc
+}
+
+// RegisterMetrics adds the connection pool metrics for the database
+// (labeled with db="primary" or the replica host) and the replica
+// health and lag to the registry.
+func RegisterMetrics(reg prometheus.Registerer, db DB) error {
+	labels := []string{"db"}
+	return reg.Register(&statsCollector{
+		db: db,
+		openConns: prometheus.NewDesc("dataapi_mysql_open_connections",
+			"Open MySQL connections", labels, nil),
+		inUse: prometheus.NewDesc("dataapi_mysql_in_use_connections",
+			"MySQL connections in use", labels, nil),
+		idle: prometheus.NewDesc("dataapi_mysql_idle_connections",
+			"Idle MySQL connections", labels, nil),
+		waitCount: prometheus.NewDesc("dataapi_mysql_wait_count_total",
+			"Waits for a MySQL connection", labels, nil),
+		waitDuration: prometheus.NewDesc("dataapi_mysql_wait_duration_seconds_total",
+			"Time waiting for a MySQL connection", labels, nil),
+		replicaHealthy: prometheus.NewDesc("dataapi_mysql_replica_healthy",
+			"If the MySQL replica is used for queries", labels, nil),
+		replicaLag: prometheus.NewDesc("dataapi_mysql_replica_lag_seconds",
+			"Replication lag of the MySQL replica at the last check", labels, nil),
+	})
+}
+
+func (c *statsCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{
+		c.openConns, c.inUse, c.idle, c.waitCount, c.waitDuration,
+		c.replicaHealthy, c.replicaLag,
+	} {
+		ch <- d
+	}
+}
+
+func (c *statsCollector) Collect(ch chan<- prometheus.Metric) {
+	c.collectPool(ch, "primary", c.db.Stats())
+
+	for _, r := range c.db.ReplicaStatus() {
+		c.collectPool(ch, r.Host, r.Stats)
+
+		healthy := 0.0
+		if r.Healthy {
+			healthy = 1
+		}
+		ch <- prometheus.MustNewConstMetric(c.replicaHealthy, prometheus.GaugeValue, healthy, r.Host)
+		ch <- prometheus.MustNewConstMetric(c.replicaLag, prometheus.GaugeValue, r.Lag.Seconds(), r.Host)
+	}
+}
+
+func (c *statsCollector) collectPool(ch chan<- prometheus.Metric, db string, stats sql.DBStats) {
+	ch <- prometheus.MustNewConstMetric(c.openConns, prometheus.GaugeValue, float64(stats.OpenConnections), db)
+	ch <- prometheus.MustNewConstMetric(c.inUse, prometheus.GaugeValue, float64(stats.InUse), db)
+	ch <- prometheus.MustNewConstMetric(c.idle, prometheus.GaugeValue, float64(stats.Idle), db)
+	ch <- prometheus.MustNewConstMetric(c.waitCount, prometheus.CounterValue, float64(stats.WaitCount), db)
+	ch <- prometheus.MustNewConstMetric(c.waitDuration, prometheus.CounterValue, stats.WaitDuration.Seconds(), db)
+}
@@ -228,6 +228,49 @@ func (ns NullServersIpVersion) Value() (driver.Value, error) {
 	return string(ns.ServersIpVersion), nil
 }
 
+type VendorZonesClientType string
+
+const (
+	VendorZonesClientTypeNtp    VendorZonesClientType = "ntp"
+	VendorZonesClientTypeSntp   VendorZonesClientType = "sntp"
+	VendorZonesClientTypeLegacy VendorZonesClientType = "legacy"
+)
+
+func (e *VendorZonesClientType) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = VendorZonesClientType(s)
+	case string:
+		*e = VendorZonesClientType(s)
+	default:
+		return fmt.Errorf("unsupported scan type for VendorZonesClientType: %T", src)
+	}
+	return nil
+}
+
+type NullVendorZonesClientType struct {
+	VendorZonesClientType VendorZonesClientType `json:"vendor_zones_client_type"`
+	Valid                 bool                  `json:"valid"` // Valid is true if VendorZonesClientType is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullVendorZonesClientType) Scan(value interface{}) error {
+	if value == nil {
+		ns.VendorZonesClientType, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.VendorZonesClientType.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullVendorZonesClientType) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.VendorZonesClientType), nil
+}
+
 type ZoneServerCountsIpVersion string
 
 const (
@@ -270,6 +313,14 @@ func (ns NullZoneServerCountsIpVersion) Value() (driver.Value, error) {
 	return string(ns.ZoneServerCountsIpVersion), nil
 }
 
+type DnsRoot struct {
+	ID              uint32 `db:"id" json:"id"`
+	Origin          string `db:"origin" json:"origin"`
+	VendorAvailable int8   `db:"vendor_available" json:"vendor_available"`
+	GeneralUse      int8   `db:"general_use" json:"general_use"`
+	NsList          string `db:"ns_list" json:"ns_list"`
+}
+
 type LogScore struct {
 	ID         uint64                   `db:"id" json:"id"`
 	MonitorID  sql.NullInt32            `db:"monitor_id" json:"monitor_id"`
@@ -282,6 +333,13 @@ type LogScore struct {
 	Attributes types.LogScoreAttributes `db:"attributes" json:"attributes"`
 }
 
+type LogScoresArchiveStatus struct {
+	ID         uint32        `db:"id" json:"id"`
+	Archiver   string        `db:"archiver" json:"archiver"`
+	LogScoreID sql.NullInt64 `db:"log_score_id" json:"log_score_id"`
+	ModifiedOn time.Time     `db:"modified_on" json:"modified_on"`
+}
+
 type Monitor struct {
 	ID            uint32                `db:"id" json:"id"`
 	IDToken       sql.NullString        `db:"id_token" json:"id_token"`
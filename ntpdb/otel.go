@@ -8,6 +8,8 @@ package ntpdb
 
 import (
 	"context"
+	"database/sql"
+	"time"
 
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
@@ -81,6 +83,146 @@ func (_d QuerierTxWithTracing) Commit(ctx context.Context) (err error) {
 	return _d.QuerierTx.Commit(ctx)
 }
 
+// GetArchiveStatus implements QuerierTx
+func (_d QuerierTxWithTracing) GetArchiveStatus(ctx context.Context, archiver string) (l1 LogScoresArchiveStatus, err error) {
+	ctx, _span := otel.Tracer(_d._instance).Start(ctx, "QuerierTx.GetArchiveStatus")
+	defer func() {
+		if _d._spanDecorator != nil {
+			_d._spanDecorator(_span, map[string]interface{}{
+				"ctx":      ctx,
+				"archiver": archiver}, map[string]interface{}{
+				"l1":  l1,
+				"err": err})
+		} else if err != nil {
+			_span.RecordError(err)
+			_span.SetStatus(_codes.Error, err.Error())
+			_span.SetAttributes(
+				attribute.String("event", "error"),
+				attribute.String("message", err.Error()),
+			)
+		}
+
+		_span.End()
+	}()
+	return _d.QuerierTx.GetArchiveStatus(ctx, archiver)
+}
+
+// GetDNSRoots implements QuerierTx
+func (_d QuerierTxWithTracing) GetDNSRoots(ctx context.Context) (da1 []DnsRoot, err error) {
+	ctx, _span := otel.Tracer(_d._instance).Start(ctx, "QuerierTx.GetDNSRoots")
+	defer func() {
+		if _d._spanDecorator != nil {
+			_d._spanDecorator(_span, map[string]interface{}{
+				"ctx": ctx}, map[string]interface{}{
+				"da1": da1,
+				"err": err})
+		} else if err != nil {
+			_span.RecordError(err)
+			_span.SetStatus(_codes.Error, err.Error())
+			_span.SetAttributes(
+				attribute.String("event", "error"),
+				attribute.String("message", err.Error()),
+			)
+		}
+
+		_span.End()
+	}()
+	return _d.QuerierTx.GetDNSRoots(ctx)
+}
+
+// GetLatestLogScoreID implements QuerierTx
+func (_d QuerierTxWithTracing) GetLatestLogScoreID(ctx context.Context) (u1 uint64, err error) {
+	ctx, _span := otel.Tracer(_d._instance).Start(ctx, "QuerierTx.GetLatestLogScoreID")
+	defer func() {
+		if _d._spanDecorator != nil {
+			_d._spanDecorator(_span, map[string]interface{}{
+				"ctx": ctx}, map[string]interface{}{
+				"u1":  u1,
+				"err": err})
+		} else if err != nil {
+			_span.RecordError(err)
+			_span.SetStatus(_codes.Error, err.Error())
+			_span.SetAttributes(
+				attribute.String("event", "error"),
+				attribute.String("message", err.Error()),
+			)
+		}
+
+		_span.End()
+	}()
+	return _d.QuerierTx.GetLatestLogScoreID(ctx)
+}
+
+// GetLatestLogScoreTs implements QuerierTx
+func (_d QuerierTxWithTracing) GetLatestLogScoreTs(ctx context.Context) (t1 time.Time, err error) {
+	ctx, _span := otel.Tracer(_d._instance).Start(ctx, "QuerierTx.GetLatestLogScoreTs")
+	defer func() {
+		if _d._spanDecorator != nil {
+			_d._spanDecorator(_span, map[string]interface{}{
+				"ctx": ctx}, map[string]interface{}{
+				"t1":  t1,
+				"err": err})
+		} else if err != nil {
+			_span.RecordError(err)
+			_span.SetStatus(_codes.Error, err.Error())
+			_span.SetAttributes(
+				attribute.String("event", "error"),
+				attribute.String("message", err.Error()),
+			)
+		}
+
+		_span.End()
+	}()
+	return _d.QuerierTx.GetLatestLogScoreTs(ctx)
+}
+
+// GetLatestZoneCountsDate implements QuerierTx
+func (_d QuerierTxWithTracing) GetLatestZoneCountsDate(ctx context.Context) (t1 time.Time, err error) {
+	ctx, _span := otel.Tracer(_d._instance).Start(ctx, "QuerierTx.GetLatestZoneCountsDate")
+	defer func() {
+		if _d._spanDecorator != nil {
+			_d._spanDecorator(_span, map[string]interface{}{
+				"ctx": ctx}, map[string]interface{}{
+				"t1":  t1,
+				"err": err})
+		} else if err != nil {
+			_span.RecordError(err)
+			_span.SetStatus(_codes.Error, err.Error())
+			_span.SetAttributes(
+				attribute.String("event", "error"),
+				attribute.String("message", err.Error()),
+			)
+		}
+
+		_span.End()
+	}()
+	return _d.QuerierTx.GetLatestZoneCountsDate(ctx)
+}
+
+// GetLogScoresAfterID implements QuerierTx
+func (_d QuerierTxWithTracing) GetLogScoresAfterID(ctx context.Context, arg GetLogScoresAfterIDParams) (la1 []LogScore, err error) {
+	ctx, _span := otel.Tracer(_d._instance).Start(ctx, "QuerierTx.GetLogScoresAfterID")
+	defer func() {
+		if _d._spanDecorator != nil {
+			_d._spanDecorator(_span, map[string]interface{}{
+				"ctx": ctx,
+				"arg": arg}, map[string]interface{}{
+				"la1": la1,
+				"err": err})
+		} else if err != nil {
+			_span.RecordError(err)
+			_span.SetStatus(_codes.Error, err.Error())
+			_span.SetAttributes(
+				attribute.String("event", "error"),
+				attribute.String("message", err.Error()),
+			)
+		}
+
+		_span.End()
+	}()
+	return _d.QuerierTx.GetLogScoresAfterID(ctx, arg)
+}
+
 // GetMonitorByNameAndIPVersion implements QuerierTx
 func (_d QuerierTxWithTracing) GetMonitorByNameAndIPVersion(ctx context.Context, arg GetMonitorByNameAndIPVersionParams) (m1 Monitor, err error) {
 	ctx, _span := otel.Tracer(_d._instance).Start(ctx, "QuerierTx.GetMonitorByNameAndIPVersion")
@@ -129,6 +271,53 @@ func (_d QuerierTxWithTracing) GetMonitorsByID(ctx context.Context, monitorids [
 	return _d.QuerierTx.GetMonitorsByID(ctx, monitorids)
 }
 
+// GetPublicAccount implements QuerierTx
+func (_d QuerierTxWithTracing) GetPublicAccount(ctx context.Context, id uint32) (g1 GetPublicAccountRow, err error) {
+	ctx, _span := otel.Tracer(_d._instance).Start(ctx, "QuerierTx.GetPublicAccount")
+	defer func() {
+		if _d._spanDecorator != nil {
+			_d._spanDecorator(_span, map[string]interface{}{
+				"ctx": ctx,
+				"id":  id}, map[string]interface{}{
+				"g1":  g1,
+				"err": err})
+		} else if err != nil {
+			_span.RecordError(err)
+			_span.SetStatus(_codes.Error, err.Error())
+			_span.SetAttributes(
+				attribute.String("event", "error"),
+				attribute.String("message", err.Error()),
+			)
+		}
+
+		_span.End()
+	}()
+	return _d.QuerierTx.GetPublicAccount(ctx, id)
+}
+
+// GetScorers implements QuerierTx
+func (_d QuerierTxWithTracing) GetScorers(ctx context.Context) (ga1 []GetScorersRow, err error) {
+	ctx, _span := otel.Tracer(_d._instance).Start(ctx, "QuerierTx.GetScorers")
+	defer func() {
+		if _d._spanDecorator != nil {
+			_d._spanDecorator(_span, map[string]interface{}{
+				"ctx": ctx}, map[string]interface{}{
+				"ga1": ga1,
+				"err": err})
+		} else if err != nil {
+			_span.RecordError(err)
+			_span.SetStatus(_codes.Error, err.Error())
+			_span.SetAttributes(
+				attribute.String("event", "error"),
+				attribute.String("message", err.Error()),
+			)
+		}
+
+		_span.End()
+	}()
+	return _d.QuerierTx.GetScorers(ctx)
+}
+
 // GetServerByID implements QuerierTx
 func (_d QuerierTxWithTracing) GetServerByID(ctx context.Context, id uint32) (s1 Server, err error) {
 	ctx, _span := otel.Tracer(_d._instance).Start(ctx, "QuerierTx.GetServerByID")
@@ -177,9 +366,9 @@ func (_d QuerierTxWithTracing) GetServerByIP(ctx context.Context, ip string) (s1
 	return _d.QuerierTx.GetServerByIP(ctx, ip)
 }
 
-// GetServerLogScores implements QuerierTx
-func (_d QuerierTxWithTracing) GetServerLogScores(ctx context.Context, arg GetServerLogScoresParams) (la1 []LogScore, err error) {
-	ctx, _span := otel.Tracer(_d._instance).Start(ctx, "QuerierTx.GetServerLogScores")
+// GetServerLogScoresByTime implements QuerierTx
+func (_d QuerierTxWithTracing) GetServerLogScoresByTime(ctx context.Context, arg GetServerLogScoresByTimeParams) (la1 []LogScore, err error) {
+	ctx, _span := otel.Tracer(_d._instance).Start(ctx, "QuerierTx.GetServerLogScoresByTime")
 	defer func() {
 		if _d._spanDecorator != nil {
 			_d._spanDecorator(_span, map[string]interface{}{
@@ -198,12 +387,12 @@ func (_d QuerierTxWithTracing) GetServerLogScores(ctx context.Context, arg GetSe
 
 		_span.End()
 	}()
-	return _d.QuerierTx.GetServerLogScores(ctx, arg)
+	return _d.QuerierTx.GetServerLogScoresByTime(ctx, arg)
 }
 
-// GetServerLogScoresByMonitorID implements QuerierTx
-func (_d QuerierTxWithTracing) GetServerLogScoresByMonitorID(ctx context.Context, arg GetServerLogScoresByMonitorIDParams) (la1 []LogScore, err error) {
-	ctx, _span := otel.Tracer(_d._instance).Start(ctx, "QuerierTx.GetServerLogScoresByMonitorID")
+// GetServerLogScoresByTimeDesc implements QuerierTx
+func (_d QuerierTxWithTracing) GetServerLogScoresByTimeDesc(ctx context.Context, arg GetServerLogScoresByTimeDescParams) (la1 []LogScore, err error) {
+	ctx, _span := otel.Tracer(_d._instance).Start(ctx, "QuerierTx.GetServerLogScoresByTimeDesc")
 	defer func() {
 		if _d._spanDecorator != nil {
 			_d._spanDecorator(_span, map[string]interface{}{
@@ -222,7 +411,31 @@ func (_d QuerierTxWithTracing) GetServerLogScoresByMonitorID(ctx context.Context
 
 		_span.End()
 	}()
-	return _d.QuerierTx.GetServerLogScoresByMonitorID(ctx, arg)
+	return _d.QuerierTx.GetServerLogScoresByTimeDesc(ctx, arg)
+}
+
+// GetServerMonitorReview implements QuerierTx
+func (_d QuerierTxWithTracing) GetServerMonitorReview(ctx context.Context, serverID uint32) (g1 GetServerMonitorReviewRow, err error) {
+	ctx, _span := otel.Tracer(_d._instance).Start(ctx, "QuerierTx.GetServerMonitorReview")
+	defer func() {
+		if _d._spanDecorator != nil {
+			_d._spanDecorator(_span, map[string]interface{}{
+				"ctx":      ctx,
+				"serverID": serverID}, map[string]interface{}{
+				"g1":  g1,
+				"err": err})
+		} else if err != nil {
+			_span.RecordError(err)
+			_span.SetStatus(_codes.Error, err.Error())
+			_span.SetAttributes(
+				attribute.String("event", "error"),
+				attribute.String("message", err.Error()),
+			)
+		}
+
+		_span.End()
+	}()
+	return _d.QuerierTx.GetServerMonitorReview(ctx, serverID)
 }
 
 // GetServerNetspeed implements QuerierTx
@@ -273,6 +486,198 @@ func (_d QuerierTxWithTracing) GetServerScores(ctx context.Context, arg GetServe
 	return _d.QuerierTx.GetServerScores(ctx, arg)
 }
 
+// GetServerURLs implements QuerierTx
+func (_d QuerierTxWithTracing) GetServerURLs(ctx context.Context, serverID uint32) (sa1 []string, err error) {
+	ctx, _span := otel.Tracer(_d._instance).Start(ctx, "QuerierTx.GetServerURLs")
+	defer func() {
+		if _d._spanDecorator != nil {
+			_d._spanDecorator(_span, map[string]interface{}{
+				"ctx":      ctx,
+				"serverID": serverID}, map[string]interface{}{
+				"sa1": sa1,
+				"err": err})
+		} else if err != nil {
+			_span.RecordError(err)
+			_span.SetStatus(_codes.Error, err.Error())
+			_span.SetAttributes(
+				attribute.String("event", "error"),
+				attribute.String("message", err.Error()),
+			)
+		}
+
+		_span.End()
+	}()
+	return _d.QuerierTx.GetServerURLs(ctx, serverID)
+}
+
+// GetServerVerification implements QuerierTx
+func (_d QuerierTxWithTracing) GetServerVerification(ctx context.Context, serverID uint32) (g1 GetServerVerificationRow, err error) {
+	ctx, _span := otel.Tracer(_d._instance).Start(ctx, "QuerierTx.GetServerVerification")
+	defer func() {
+		if _d._spanDecorator != nil {
+			_d._spanDecorator(_span, map[string]interface{}{
+				"ctx":      ctx,
+				"serverID": serverID}, map[string]interface{}{
+				"g1":  g1,
+				"err": err})
+		} else if err != nil {
+			_span.RecordError(err)
+			_span.SetStatus(_codes.Error, err.Error())
+			_span.SetAttributes(
+				attribute.String("event", "error"),
+				attribute.String("message", err.Error()),
+			)
+		}
+
+		_span.End()
+	}()
+	return _d.QuerierTx.GetServerVerification(ctx, serverID)
+}
+
+// GetServerVerificationHistory implements QuerierTx
+func (_d QuerierTxWithTracing) GetServerVerificationHistory(ctx context.Context, serverID uint32) (ga1 []GetServerVerificationHistoryRow, err error) {
+	ctx, _span := otel.Tracer(_d._instance).Start(ctx, "QuerierTx.GetServerVerificationHistory")
+	defer func() {
+		if _d._spanDecorator != nil {
+			_d._spanDecorator(_span, map[string]interface{}{
+				"ctx":      ctx,
+				"serverID": serverID}, map[string]interface{}{
+				"ga1": ga1,
+				"err": err})
+		} else if err != nil {
+			_span.RecordError(err)
+			_span.SetStatus(_codes.Error, err.Error())
+			_span.SetAttributes(
+				attribute.String("event", "error"),
+				attribute.String("message", err.Error()),
+			)
+		}
+
+		_span.End()
+	}()
+	return _d.QuerierTx.GetServerVerificationHistory(ctx, serverID)
+}
+
+// GetServerZoneNetspeedHistory implements QuerierTx
+func (_d QuerierTxWithTracing) GetServerZoneNetspeedHistory(ctx context.Context, arg GetServerZoneNetspeedHistoryParams) (ga1 []GetServerZoneNetspeedHistoryRow, err error) {
+	ctx, _span := otel.Tracer(_d._instance).Start(ctx, "QuerierTx.GetServerZoneNetspeedHistory")
+	defer func() {
+		if _d._spanDecorator != nil {
+			_d._spanDecorator(_span, map[string]interface{}{
+				"ctx": ctx,
+				"arg": arg}, map[string]interface{}{
+				"ga1": ga1,
+				"err": err})
+		} else if err != nil {
+			_span.RecordError(err)
+			_span.SetStatus(_codes.Error, err.Error())
+			_span.SetAttributes(
+				attribute.String("event", "error"),
+				attribute.String("message", err.Error()),
+			)
+		}
+
+		_span.End()
+	}()
+	return _d.QuerierTx.GetServerZoneNetspeedHistory(ctx, arg)
+}
+
+// GetServerZones implements QuerierTx
+func (_d QuerierTxWithTracing) GetServerZones(ctx context.Context, serverID uint32) (sa1 []string, err error) {
+	ctx, _span := otel.Tracer(_d._instance).Start(ctx, "QuerierTx.GetServerZones")
+	defer func() {
+		if _d._spanDecorator != nil {
+			_d._spanDecorator(_span, map[string]interface{}{
+				"ctx":      ctx,
+				"serverID": serverID}, map[string]interface{}{
+				"sa1": sa1,
+				"err": err})
+		} else if err != nil {
+			_span.RecordError(err)
+			_span.SetStatus(_codes.Error, err.Error())
+			_span.SetAttributes(
+				attribute.String("event", "error"),
+				attribute.String("message", err.Error()),
+			)
+		}
+
+		_span.End()
+	}()
+	return _d.QuerierTx.GetServerZones(ctx, serverID)
+}
+
+// GetServersByAccount implements QuerierTx
+func (_d QuerierTxWithTracing) GetServersByAccount(ctx context.Context, account string) (sa1 []Server, err error) {
+	ctx, _span := otel.Tracer(_d._instance).Start(ctx, "QuerierTx.GetServersByAccount")
+	defer func() {
+		if _d._spanDecorator != nil {
+			_d._spanDecorator(_span, map[string]interface{}{
+				"ctx":     ctx,
+				"account": account}, map[string]interface{}{
+				"sa1": sa1,
+				"err": err})
+		} else if err != nil {
+			_span.RecordError(err)
+			_span.SetStatus(_codes.Error, err.Error())
+			_span.SetAttributes(
+				attribute.String("event", "error"),
+				attribute.String("message", err.Error()),
+			)
+		}
+
+		_span.End()
+	}()
+	return _d.QuerierTx.GetServersByAccount(ctx, account)
+}
+
+// GetServersByHostname implements QuerierTx
+func (_d QuerierTxWithTracing) GetServersByHostname(ctx context.Context, hostname sql.NullString) (sa1 []Server, err error) {
+	ctx, _span := otel.Tracer(_d._instance).Start(ctx, "QuerierTx.GetServersByHostname")
+	defer func() {
+		if _d._spanDecorator != nil {
+			_d._spanDecorator(_span, map[string]interface{}{
+				"ctx":      ctx,
+				"hostname": hostname}, map[string]interface{}{
+				"sa1": sa1,
+				"err": err})
+		} else if err != nil {
+			_span.RecordError(err)
+			_span.SetStatus(_codes.Error, err.Error())
+			_span.SetAttributes(
+				attribute.String("event", "error"),
+				attribute.String("message", err.Error()),
+			)
+		}
+
+		_span.End()
+	}()
+	return _d.QuerierTx.GetServersByHostname(ctx, hostname)
+}
+
+// GetVendorZone implements QuerierTx
+func (_d QuerierTxWithTracing) GetVendorZone(ctx context.Context, arg GetVendorZoneParams) (g1 GetVendorZoneRow, err error) {
+	ctx, _span := otel.Tracer(_d._instance).Start(ctx, "QuerierTx.GetVendorZone")
+	defer func() {
+		if _d._spanDecorator != nil {
+			_d._spanDecorator(_span, map[string]interface{}{
+				"ctx": ctx,
+				"arg": arg}, map[string]interface{}{
+				"g1":  g1,
+				"err": err})
+		} else if err != nil {
+			_span.RecordError(err)
+			_span.SetStatus(_codes.Error, err.Error())
+			_span.SetAttributes(
+				attribute.String("event", "error"),
+				attribute.String("message", err.Error()),
+			)
+		}
+
+		_span.End()
+	}()
+	return _d.QuerierTx.GetVendorZone(ctx, arg)
+}
+
 // GetZoneByName implements QuerierTx
 func (_d QuerierTxWithTracing) GetZoneByName(ctx context.Context, name string) (z1 Zone, err error) {
 	ctx, _span := otel.Tracer(_d._instance).Start(ctx, "QuerierTx.GetZoneByName")
@@ -344,6 +749,30 @@ func (_d QuerierTxWithTracing) GetZoneStatsData(ctx context.Context) (ga1 []GetZ
 	return _d.QuerierTx.GetZoneStatsData(ctx)
 }
 
+// GetZoneStatsHistory implements QuerierTx
+func (_d QuerierTxWithTracing) GetZoneStatsHistory(ctx context.Context, arg GetZoneStatsHistoryParams) (ga1 []GetZoneStatsHistoryRow, err error) {
+	ctx, _span := otel.Tracer(_d._instance).Start(ctx, "QuerierTx.GetZoneStatsHistory")
+	defer func() {
+		if _d._spanDecorator != nil {
+			_d._spanDecorator(_span, map[string]interface{}{
+				"ctx": ctx,
+				"arg": arg}, map[string]interface{}{
+				"ga1": ga1,
+				"err": err})
+		} else if err != nil {
+			_span.RecordError(err)
+			_span.SetStatus(_codes.Error, err.Error())
+			_span.SetAttributes(
+				attribute.String("event", "error"),
+				attribute.String("message", err.Error()),
+			)
+		}
+
+		_span.End()
+	}()
+	return _d.QuerierTx.GetZoneStatsHistory(ctx, arg)
+}
+
 // GetZoneStatsV2 implements QuerierTx
 func (_d QuerierTxWithTracing) GetZoneStatsV2(ctx context.Context, ip string) (ga1 []GetZoneStatsV2Row, err error) {
 	ctx, _span := otel.Tracer(_d._instance).Start(ctx, "QuerierTx.GetZoneStatsV2")
@@ -368,6 +797,29 @@ func (_d QuerierTxWithTracing) GetZoneStatsV2(ctx context.Context, ip string) (g
 	return _d.QuerierTx.GetZoneStatsV2(ctx, ip)
 }
 
+// InsertArchiveStatus implements QuerierTx
+func (_d QuerierTxWithTracing) InsertArchiveStatus(ctx context.Context, archiver string) (err error) {
+	ctx, _span := otel.Tracer(_d._instance).Start(ctx, "QuerierTx.InsertArchiveStatus")
+	defer func() {
+		if _d._spanDecorator != nil {
+			_d._spanDecorator(_span, map[string]interface{}{
+				"ctx":      ctx,
+				"archiver": archiver}, map[string]interface{}{
+				"err": err})
+		} else if err != nil {
+			_span.RecordError(err)
+			_span.SetStatus(_codes.Error, err.Error())
+			_span.SetAttributes(
+				attribute.String("event", "error"),
+				attribute.String("message", err.Error()),
+			)
+		}
+
+		_span.End()
+	}()
+	return _d.QuerierTx.InsertArchiveStatus(ctx, archiver)
+}
+
 // Rollback implements QuerierTx
 func (_d QuerierTxWithTracing) Rollback(ctx context.Context) (err error) {
 	ctx, _span := otel.Tracer(_d._instance).Start(ctx, "QuerierTx.Rollback")
@@ -389,3 +841,50 @@ func (_d QuerierTxWithTracing) Rollback(ctx context.Context) (err error) {
 	}()
 	return _d.QuerierTx.Rollback(ctx)
 }
+
+// SearchServers implements QuerierTx
+func (_d QuerierTxWithTracing) SearchServers(ctx context.Context, arg SearchServersParams) (sa1 []Server, err error) {
+	ctx, _span := otel.Tracer(_d._instance).Start(ctx, "QuerierTx.SearchServers")
+	defer func() {
+		if _d._spanDecorator != nil {
+			_d._spanDecorator(_span, map[string]interface{}{
+				"ctx": ctx,
+				"arg": arg}, map[string]interface{}{
+				"sa1": sa1,
+				"err": err})
+		} else if err != nil {
+			_span.RecordError(err)
+			_span.SetStatus(_codes.Error, err.Error())
+			_span.SetAttributes(
+				attribute.String("event", "error"),
+				attribute.String("message", err.Error()),
+			)
+		}
+
+		_span.End()
+	}()
+	return _d.QuerierTx.SearchServers(ctx, arg)
+}
+
+// UpdateArchiveStatus implements QuerierTx
+func (_d QuerierTxWithTracing) UpdateArchiveStatus(ctx context.Context, arg UpdateArchiveStatusParams) (err error) {
+	ctx, _span := otel.Tracer(_d._instance).Start(ctx, "QuerierTx.UpdateArchiveStatus")
+	defer func() {
+		if _d._spanDecorator != nil {
+			_d._spanDecorator(_span, map[string]interface{}{
+				"ctx": ctx,
+				"arg": arg}, map[string]interface{}{
+				"err": err})
+		} else if err != nil {
+			_span.RecordError(err)
+			_span.SetStatus(_codes.Error, err.Error())
+			_span.SetAttributes(
+				attribute.String("event", "error"),
+				attribute.String("message", err.Error()),
+			)
+		}
+
+		_span.End()
+	}()
+	return _d.QuerierTx.UpdateArchiveStatus(ctx, arg)
+}
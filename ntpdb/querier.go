@@ -6,21 +6,44 @@ package ntpdb
 
 import (
 	"context"
+	"database/sql"
+	"time"
 )
 
 type Querier interface {
+	GetArchiveStatus(ctx context.Context, archiver string) (LogScoresArchiveStatus, error)
+	GetDNSRoots(ctx context.Context) ([]DnsRoot, error)
+	GetLatestLogScoreID(ctx context.Context) (uint64, error)
+	GetLatestLogScoreTs(ctx context.Context) (time.Time, error)
+	GetLatestZoneCountsDate(ctx context.Context) (time.Time, error)
+	GetLogScoresAfterID(ctx context.Context, arg GetLogScoresAfterIDParams) ([]LogScore, error)
 	GetMonitorByNameAndIPVersion(ctx context.Context, arg GetMonitorByNameAndIPVersionParams) (Monitor, error)
 	GetMonitorsByID(ctx context.Context, monitorids []uint32) ([]Monitor, error)
+	GetPublicAccount(ctx context.Context, id uint32) (GetPublicAccountRow, error)
+	GetScorers(ctx context.Context) ([]GetScorersRow, error)
 	GetServerByID(ctx context.Context, id uint32) (Server, error)
 	GetServerByIP(ctx context.Context, ip string) (Server, error)
-	GetServerLogScores(ctx context.Context, arg GetServerLogScoresParams) ([]LogScore, error)
-	GetServerLogScoresByMonitorID(ctx context.Context, arg GetServerLogScoresByMonitorIDParams) ([]LogScore, error)
+	GetServerLogScoresByTime(ctx context.Context, arg GetServerLogScoresByTimeParams) ([]LogScore, error)
+	GetServerLogScoresByTimeDesc(ctx context.Context, arg GetServerLogScoresByTimeDescParams) ([]LogScore, error)
+	GetServerMonitorReview(ctx context.Context, serverID uint32) (GetServerMonitorReviewRow, error)
 	GetServerNetspeed(ctx context.Context, ip string) (uint32, error)
 	GetServerScores(ctx context.Context, arg GetServerScoresParams) ([]GetServerScoresRow, error)
+	GetServerURLs(ctx context.Context, serverID uint32) ([]string, error)
+	GetServerVerification(ctx context.Context, serverID uint32) (GetServerVerificationRow, error)
+	GetServerVerificationHistory(ctx context.Context, serverID uint32) ([]GetServerVerificationHistoryRow, error)
+	GetServerZoneNetspeedHistory(ctx context.Context, arg GetServerZoneNetspeedHistoryParams) ([]GetServerZoneNetspeedHistoryRow, error)
+	GetServerZones(ctx context.Context, serverID uint32) ([]string, error)
+	GetServersByAccount(ctx context.Context, account string) ([]Server, error)
+	GetServersByHostname(ctx context.Context, hostname sql.NullString) ([]Server, error)
+	GetVendorZone(ctx context.Context, arg GetVendorZoneParams) (GetVendorZoneRow, error)
 	GetZoneByName(ctx context.Context, name string) (Zone, error)
 	GetZoneCounts(ctx context.Context, zoneID uint32) ([]ZoneServerCount, error)
 	GetZoneStatsData(ctx context.Context) ([]GetZoneStatsDataRow, error)
+	GetZoneStatsHistory(ctx context.Context, arg GetZoneStatsHistoryParams) ([]GetZoneStatsHistoryRow, error)
 	GetZoneStatsV2(ctx context.Context, ip string) ([]GetZoneStatsV2Row, error)
+	InsertArchiveStatus(ctx context.Context, archiver string) error
+	SearchServers(ctx context.Context, arg SearchServersParams) ([]Server, error)
+	UpdateArchiveStatus(ctx context.Context, arg UpdateArchiveStatusParams) error
 }
 
 var _ Querier = (*Queries)(nil)
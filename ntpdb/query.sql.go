@@ -12,6 +12,144 @@ import (
 	"time"
 )
 
+const getArchiveStatus = `-- name: GetArchiveStatus :one
+select id, archiver, log_score_id, modified_on from log_scores_archive_status
+where
+  archiver = ?
+  for update
+`
+
+func (q *Queries) GetArchiveStatus(ctx context.Context, archiver string) (LogScoresArchiveStatus, error) {
+	row := q.db.QueryRowContext(ctx, getArchiveStatus, archiver)
+	var i LogScoresArchiveStatus
+	err := row.Scan(
+		&i.ID,
+		&i.Archiver,
+		&i.LogScoreID,
+		&i.ModifiedOn,
+	)
+	return i, err
+}
+
+const getDNSRoots = `-- name: GetDNSRoots :many
+select id, origin, vendor_available, general_use, ns_list from dns_roots
+order by origin
+`
+
+func (q *Queries) GetDNSRoots(ctx context.Context) ([]DnsRoot, error) {
+	rows, err := q.db.QueryContext(ctx, getDNSRoots)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []DnsRoot
+	for rows.Next() {
+		var i DnsRoot
+		if err := rows.Scan(
+			&i.ID,
+			&i.Origin,
+			&i.VendorAvailable,
+			&i.GeneralUse,
+			&i.NsList,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getLatestLogScoreID = `-- name: GetLatestLogScoreID :one
+select id from log_scores
+  order by id desc
+  limit 1
+`
+
+func (q *Queries) GetLatestLogScoreID(ctx context.Context) (uint64, error) {
+	row := q.db.QueryRowContext(ctx, getLatestLogScoreID)
+	var id uint64
+	err := row.Scan(&id)
+	return id, err
+}
+
+const getLatestLogScoreTs = `-- name: GetLatestLogScoreTs :one
+select ts from log_scores
+  order by id desc
+  limit 1
+`
+
+func (q *Queries) GetLatestLogScoreTs(ctx context.Context) (time.Time, error) {
+	row := q.db.QueryRowContext(ctx, getLatestLogScoreTs)
+	var ts time.Time
+	err := row.Scan(&ts)
+	return ts, err
+}
+
+const getLatestZoneCountsDate = `-- name: GetLatestZoneCountsDate :one
+select date from zone_server_counts
+  order by date desc
+  limit 1
+`
+
+func (q *Queries) GetLatestZoneCountsDate(ctx context.Context) (time.Time, error) {
+	row := q.db.QueryRowContext(ctx, getLatestZoneCountsDate)
+	var date time.Time
+	err := row.Scan(&date)
+	return date, err
+}
+
+const getLogScoresAfterID = `-- name: GetLogScoresAfterID :many
+select id, monitor_id, server_id, ts, score, step, offset, rtt, attributes from log_scores
+where
+  id > ?
+  order by id
+  limit ?
+`
+
+type GetLogScoresAfterIDParams struct {
+	ID    uint64 `db:"id" json:"id"`
+	Limit int32  `db:"limit" json:"limit"`
+}
+
+func (q *Queries) GetLogScoresAfterID(ctx context.Context, arg GetLogScoresAfterIDParams) ([]LogScore, error) {
+	rows, err := q.db.QueryContext(ctx, getLogScoresAfterID, arg.ID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []LogScore
+	for rows.Next() {
+		var i LogScore
+		if err := rows.Scan(
+			&i.ID,
+			&i.MonitorID,
+			&i.ServerID,
+			&i.Ts,
+			&i.Score,
+			&i.Step,
+			&i.Offset,
+			&i.Rtt,
+			&i.Attributes,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getMonitorByNameAndIPVersion = `-- name: GetMonitorByNameAndIPVersion :one
 select id, id_token, type, user_id, account_id, hostname, location, ip, ip_version, tls_name, api_key, status, config, client_version, last_seen, last_submit, created_on, deleted_on, is_current from monitors
 where
@@ -113,6 +251,86 @@ func (q *Queries) GetMonitorsByID(ctx context.Context, monitorids []uint32) ([]M
 	return items, nil
 }
 
+const getPublicAccount = `-- name: GetPublicAccount :one
+select id_token, name, organization_name, organization_url, url_slug
+from accounts
+where
+  id = ? AND
+  public_profile = 1
+`
+
+type GetPublicAccountRow struct {
+	IDToken          sql.NullString `db:"id_token" json:"id_token"`
+	Name             sql.NullString `db:"name" json:"name"`
+	OrganizationName sql.NullString `db:"organization_name" json:"organization_name"`
+	OrganizationUrl  sql.NullString `db:"organization_url" json:"organization_url"`
+	UrlSlug          sql.NullString `db:"url_slug" json:"url_slug"`
+}
+
+func (q *Queries) GetPublicAccount(ctx context.Context, id uint32) (GetPublicAccountRow, error) {
+	row := q.db.QueryRowContext(ctx, getPublicAccount, id)
+	var i GetPublicAccountRow
+	err := row.Scan(
+		&i.IDToken,
+		&i.Name,
+		&i.OrganizationName,
+		&i.OrganizationUrl,
+		&i.UrlSlug,
+	)
+	return i, err
+}
+
+const getScorers = `-- name: GetScorers :many
+select m.id, m.hostname, m.status,
+  ss.log_score_id, ss.modified_on, ls.ts as log_score_ts
+from monitors m
+  left join scorer_status ss on (ss.scorer_id=m.id)
+  left join log_scores ls on (ls.id=ss.log_score_id)
+where
+  m.type = 'score' AND
+  m.deleted_on IS NULL
+order by m.hostname, m.id
+`
+
+type GetScorersRow struct {
+	ID         uint32         `db:"id" json:"id"`
+	Hostname   string         `db:"hostname" json:"hostname"`
+	Status     MonitorsStatus `db:"status" json:"status"`
+	LogScoreID sql.NullInt64  `db:"log_score_id" json:"log_score_id"`
+	ModifiedOn sql.NullTime   `db:"modified_on" json:"modified_on"`
+	LogScoreTs sql.NullTime   `db:"log_score_ts" json:"log_score_ts"`
+}
+
+func (q *Queries) GetScorers(ctx context.Context) ([]GetScorersRow, error) {
+	rows, err := q.db.QueryContext(ctx, getScorers)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetScorersRow
+	for rows.Next() {
+		var i GetScorersRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Hostname,
+			&i.Status,
+			&i.LogScoreID,
+			&i.ModifiedOn,
+			&i.LogScoreTs,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getServerByID = `-- name: GetServerByID :one
 select id, ip, ip_version, user_id, account_id, hostname, stratum, in_pool, in_server_list, netspeed, netspeed_target, created_on, updated_on, score_ts, score_raw, deletion_on, flags from servers
 where
@@ -175,21 +393,34 @@ func (q *Queries) GetServerByIP(ctx context.Context, ip string) (Server, error)
 	return i, err
 }
 
-const getServerLogScores = `-- name: GetServerLogScores :many
-select id, monitor_id, server_id, ts, score, step, offset, rtt, attributes from log_scores
+const getServerLogScoresByTime = `-- name: GetServerLogScoresByTime :many
+select id, monitor_id, server_id, ts, score, step, offset, rtt, attributes from log_scores USE INDEX (log_scores_server_ts_idx)
 where
-  server_id = ?
-  order by ts desc
+  server_id = ? AND
+  ts > ? AND
+  ts <= ? AND
+  (? IS NULL OR monitor_id = ?)
+  order by ts
   limit ?
 `
 
-type GetServerLogScoresParams struct {
-	ServerID uint32 `db:"server_id" json:"server_id"`
-	Limit    int32  `db:"limit" json:"limit"`
+type GetServerLogScoresByTimeParams struct {
+	ServerID  uint32        `db:"server_id" json:"server_id"`
+	Since     time.Time     `db:"since" json:"since"`
+	Until     time.Time     `db:"until" json:"until"`
+	MonitorID sql.NullInt32 `db:"monitor_id" json:"monitor_id"`
+	Limit     int32         `db:"limit" json:"limit"`
 }
 
-func (q *Queries) GetServerLogScores(ctx context.Context, arg GetServerLogScoresParams) ([]LogScore, error) {
-	rows, err := q.db.QueryContext(ctx, getServerLogScores, arg.ServerID, arg.Limit)
+func (q *Queries) GetServerLogScoresByTime(ctx context.Context, arg GetServerLogScoresByTimeParams) ([]LogScore, error) {
+	rows, err := q.db.QueryContext(ctx, getServerLogScoresByTime,
+		arg.ServerID,
+		arg.Since,
+		arg.Until,
+		arg.MonitorID,
+		arg.MonitorID,
+		arg.Limit,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -221,23 +452,34 @@ func (q *Queries) GetServerLogScores(ctx context.Context, arg GetServerLogScores
 	return items, nil
 }
 
-const getServerLogScoresByMonitorID = `-- name: GetServerLogScoresByMonitorID :many
-select id, monitor_id, server_id, ts, score, step, offset, rtt, attributes from log_scores
+const getServerLogScoresByTimeDesc = `-- name: GetServerLogScoresByTimeDesc :many
+select id, monitor_id, server_id, ts, score, step, offset, rtt, attributes from log_scores USE INDEX (log_scores_server_ts_idx)
 where
   server_id = ? AND
-  monitor_id = ?
+  ts > ? AND
+  ts <= ? AND
+  (? IS NULL OR monitor_id = ?)
   order by ts desc
   limit ?
 `
 
-type GetServerLogScoresByMonitorIDParams struct {
+type GetServerLogScoresByTimeDescParams struct {
 	ServerID  uint32        `db:"server_id" json:"server_id"`
+	Since     time.Time     `db:"since" json:"since"`
+	Until     time.Time     `db:"until" json:"until"`
 	MonitorID sql.NullInt32 `db:"monitor_id" json:"monitor_id"`
 	Limit     int32         `db:"limit" json:"limit"`
 }
 
-func (q *Queries) GetServerLogScoresByMonitorID(ctx context.Context, arg GetServerLogScoresByMonitorIDParams) ([]LogScore, error) {
-	rows, err := q.db.QueryContext(ctx, getServerLogScoresByMonitorID, arg.ServerID, arg.MonitorID, arg.Limit)
+func (q *Queries) GetServerLogScoresByTimeDesc(ctx context.Context, arg GetServerLogScoresByTimeDescParams) ([]LogScore, error) {
+	rows, err := q.db.QueryContext(ctx, getServerLogScoresByTimeDesc,
+		arg.ServerID,
+		arg.Since,
+		arg.Until,
+		arg.MonitorID,
+		arg.MonitorID,
+		arg.Limit,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -269,6 +511,24 @@ func (q *Queries) GetServerLogScoresByMonitorID(ctx context.Context, arg GetServ
 	return items, nil
 }
 
+const getServerMonitorReview = `-- name: GetServerMonitorReview :one
+select last_review, next_review
+from servers_monitor_review
+where server_id = ?
+`
+
+type GetServerMonitorReviewRow struct {
+	LastReview sql.NullTime `db:"last_review" json:"last_review"`
+	NextReview sql.NullTime `db:"next_review" json:"next_review"`
+}
+
+func (q *Queries) GetServerMonitorReview(ctx context.Context, serverID uint32) (GetServerMonitorReviewRow, error) {
+	row := q.db.QueryRowContext(ctx, getServerMonitorReview, serverID)
+	var i GetServerMonitorReviewRow
+	err := row.Scan(&i.LastReview, &i.NextReview)
+	return i, err
+}
+
 const getServerNetspeed = `-- name: GetServerNetspeed :one
 select netspeed from servers where ip = ?
 `
@@ -351,6 +611,304 @@ func (q *Queries) GetServerScores(ctx context.Context, arg GetServerScoresParams
 	return items, nil
 }
 
+const getServerURLs = `-- name: GetServerURLs :many
+select url from server_urls
+where server_id = ?
+order by id
+`
+
+func (q *Queries) GetServerURLs(ctx context.Context, serverID uint32) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, getServerURLs, serverID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var url string
+		if err := rows.Scan(&url); err != nil {
+			return nil, err
+		}
+		items = append(items, url)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getServerVerification = `-- name: GetServerVerification :one
+select verified_on, created_on
+from server_verifications
+where server_id = ?
+`
+
+type GetServerVerificationRow struct {
+	VerifiedOn sql.NullTime `db:"verified_on" json:"verified_on"`
+	CreatedOn  time.Time    `db:"created_on" json:"created_on"`
+}
+
+func (q *Queries) GetServerVerification(ctx context.Context, serverID uint32) (GetServerVerificationRow, error) {
+	row := q.db.QueryRowContext(ctx, getServerVerification, serverID)
+	var i GetServerVerificationRow
+	err := row.Scan(&i.VerifiedOn, &i.CreatedOn)
+	return i, err
+}
+
+const getServerVerificationHistory = `-- name: GetServerVerificationHistory :many
+select id, verified_on, created_on
+from server_verifications_history
+where server_id = ?
+order by created_on desc, id desc
+limit 100
+`
+
+type GetServerVerificationHistoryRow struct {
+	ID         uint32       `db:"id" json:"id"`
+	VerifiedOn sql.NullTime `db:"verified_on" json:"verified_on"`
+	CreatedOn  time.Time    `db:"created_on" json:"created_on"`
+}
+
+func (q *Queries) GetServerVerificationHistory(ctx context.Context, serverID uint32) ([]GetServerVerificationHistoryRow, error) {
+	rows, err := q.db.QueryContext(ctx, getServerVerificationHistory, serverID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetServerVerificationHistoryRow
+	for rows.Next() {
+		var i GetServerVerificationHistoryRow
+		if err := rows.Scan(&i.ID, &i.VerifiedOn, &i.CreatedOn); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getServerZoneNetspeedHistory = `-- name: GetServerZoneNetspeedHistory :many
+select z.name as zone_name, zc.date, zc.netspeed_active
+from zone_server_counts zc
+  inner join zones z on (z.id=zc.zone_id)
+  inner join server_zones sz on (sz.zone_id=zc.zone_id)
+  inner join servers s on (s.id=sz.server_id AND s.ip_version=zc.ip_version)
+where
+  s.ip = ? AND
+  zc.date >= ? AND
+  zc.date <= ?
+order by zc.date, z.name
+`
+
+type GetServerZoneNetspeedHistoryParams struct {
+	ServerIP string    `db:"server_ip" json:"server_ip"`
+	FromDate time.Time `db:"from_date" json:"from_date"`
+	ToDate   time.Time `db:"to_date" json:"to_date"`
+}
+
+type GetServerZoneNetspeedHistoryRow struct {
+	ZoneName       string    `db:"zone_name" json:"zone_name"`
+	Date           time.Time `db:"date" json:"date"`
+	NetspeedActive uint32    `db:"netspeed_active" json:"netspeed_active"`
+}
+
+func (q *Queries) GetServerZoneNetspeedHistory(ctx context.Context, arg GetServerZoneNetspeedHistoryParams) ([]GetServerZoneNetspeedHistoryRow, error) {
+	rows, err := q.db.QueryContext(ctx, getServerZoneNetspeedHistory, arg.ServerIP, arg.FromDate, arg.ToDate)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetServerZoneNetspeedHistoryRow
+	for rows.Next() {
+		var i GetServerZoneNetspeedHistoryRow
+		if err := rows.Scan(&i.ZoneName, &i.Date, &i.NetspeedActive); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getServerZones = `-- name: GetServerZones :many
+select z.name from zones z
+  inner join server_zones sz on (sz.zone_id=z.id)
+where sz.server_id = ?
+order by z.name
+`
+
+func (q *Queries) GetServerZones(ctx context.Context, serverID uint32) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, getServerZones, serverID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		items = append(items, name)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getServersByAccount = `-- name: GetServersByAccount :many
+select s.id, s.ip, s.ip_version, s.user_id, s.account_id, s.hostname, s.stratum, s.in_pool, s.in_server_list, s.netspeed, s.netspeed_target, s.created_on, s.updated_on, s.score_ts, s.score_raw, s.deletion_on, s.flags from servers s
+  inner join accounts a on (a.id=s.account_id)
+where
+  (a.id_token = ? OR a.url_slug = ?) AND
+  a.public_profile = 1 AND
+  (s.deletion_on IS NULL OR s.deletion_on > NOW())
+order by s.ip_version, s.ip
+`
+
+func (q *Queries) GetServersByAccount(ctx context.Context, account string) ([]Server, error) {
+	rows, err := q.db.QueryContext(ctx, getServersByAccount, account, account)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Server
+	for rows.Next() {
+		var i Server
+		if err := rows.Scan(
+			&i.ID,
+			&i.Ip,
+			&i.IpVersion,
+			&i.UserID,
+			&i.AccountID,
+			&i.Hostname,
+			&i.Stratum,
+			&i.InPool,
+			&i.InServerList,
+			&i.Netspeed,
+			&i.NetspeedTarget,
+			&i.CreatedOn,
+			&i.UpdatedOn,
+			&i.ScoreTs,
+			&i.ScoreRaw,
+			&i.DeletionOn,
+			&i.Flags,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getServersByHostname = `-- name: GetServersByHostname :many
+select s.id, s.ip, s.ip_version, s.user_id, s.account_id, s.hostname, s.stratum, s.in_pool, s.in_server_list, s.netspeed, s.netspeed_target, s.created_on, s.updated_on, s.score_ts, s.score_raw, s.deletion_on, s.flags from servers s
+  inner join accounts a on (a.id=s.account_id)
+where
+  s.hostname = ? AND
+  a.public_profile = 1 AND
+  (s.deletion_on IS NULL OR s.deletion_on > NOW())
+order by s.ip_version, s.ip
+`
+
+func (q *Queries) GetServersByHostname(ctx context.Context, hostname sql.NullString) ([]Server, error) {
+	rows, err := q.db.QueryContext(ctx, getServersByHostname, hostname)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Server
+	for rows.Next() {
+		var i Server
+		if err := rows.Scan(
+			&i.ID,
+			&i.Ip,
+			&i.IpVersion,
+			&i.UserID,
+			&i.AccountID,
+			&i.Hostname,
+			&i.Stratum,
+			&i.InPool,
+			&i.InServerList,
+			&i.Netspeed,
+			&i.NetspeedTarget,
+			&i.CreatedOn,
+			&i.UpdatedOn,
+			&i.ScoreTs,
+			&i.ScoreRaw,
+			&i.DeletionOn,
+			&i.Flags,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getVendorZone = `-- name: GetVendorZone :one
+select vz.id, vz.zone_name, vz.client_type, dr.origin
+from vendor_zones vz
+  inner join dns_roots dr on (dr.id=vz.dns_root_id)
+where
+  vz.zone_name = ? AND
+  dr.origin = ? AND
+  vz.status = 'Approved'
+`
+
+type GetVendorZoneParams struct {
+	ZoneName string `db:"zone_name" json:"zone_name"`
+	Origin   string `db:"origin" json:"origin"`
+}
+
+type GetVendorZoneRow struct {
+	ID         uint32                `db:"id" json:"id"`
+	ZoneName   string                `db:"zone_name" json:"zone_name"`
+	ClientType VendorZonesClientType `db:"client_type" json:"client_type"`
+	Origin     string                `db:"origin" json:"origin"`
+}
+
+func (q *Queries) GetVendorZone(ctx context.Context, arg GetVendorZoneParams) (GetVendorZoneRow, error) {
+	row := q.db.QueryRowContext(ctx, getVendorZone, arg.ZoneName, arg.Origin)
+	var i GetVendorZoneRow
+	err := row.Scan(
+		&i.ID,
+		&i.ZoneName,
+		&i.ClientType,
+		&i.Origin,
+	)
+	return i, err
+}
+
 const getZoneByName = `-- name: GetZoneByName :one
 select id, name, description, parent_id, dns from zones
 where
@@ -455,6 +1013,59 @@ func (q *Queries) GetZoneStatsData(ctx context.Context) ([]GetZoneStatsDataRow,
 	return items, nil
 }
 
+const getZoneStatsHistory = `-- name: GetZoneStatsHistory :many
+SELECT zc.date, z.name, zc.ip_version, count_active, count_registered, netspeed_active
+FROM zone_server_counts zc USE INDEX (date_idx)
+  INNER JOIN zones z
+    ON(zc.zone_id=z.id)
+  WHERE date >= ? AND date <= ?
+ORDER BY date, name
+`
+
+type GetZoneStatsHistoryParams struct {
+	FromDate time.Time `db:"from_date" json:"from_date"`
+	ToDate   time.Time `db:"to_date" json:"to_date"`
+}
+
+type GetZoneStatsHistoryRow struct {
+	Date            time.Time                 `db:"date" json:"date"`
+	Name            string                    `db:"name" json:"name"`
+	IpVersion       ZoneServerCountsIpVersion `db:"ip_version" json:"ip_version"`
+	CountActive     uint32                    `db:"count_active" json:"count_active"`
+	CountRegistered uint32                    `db:"count_registered" json:"count_registered"`
+	NetspeedActive  uint32                    `db:"netspeed_active" json:"netspeed_active"`
+}
+
+func (q *Queries) GetZoneStatsHistory(ctx context.Context, arg GetZoneStatsHistoryParams) ([]GetZoneStatsHistoryRow, error) {
+	rows, err := q.db.QueryContext(ctx, getZoneStatsHistory, arg.FromDate, arg.ToDate)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetZoneStatsHistoryRow
+	for rows.Next() {
+		var i GetZoneStatsHistoryRow
+		if err := rows.Scan(
+			&i.Date,
+			&i.Name,
+			&i.IpVersion,
+			&i.CountActive,
+			&i.CountRegistered,
+			&i.NetspeedActive,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getZoneStatsV2 = `-- name: GetZoneStatsV2 :many
 select zone_name, netspeed_active+0 as netspeed_active FROM (
 SELECT
@@ -510,3 +1121,148 @@ func (q *Queries) GetZoneStatsV2(ctx context.Context, ip string) ([]GetZoneStats
 	}
 	return items, nil
 }
+
+const insertArchiveStatus = `-- name: InsertArchiveStatus :exec
+insert into log_scores_archive_status
+  (archiver, log_score_id)
+  values (?, NULL)
+`
+
+func (q *Queries) InsertArchiveStatus(ctx context.Context, archiver string) error {
+	_, err := q.db.ExecContext(ctx, insertArchiveStatus, archiver)
+	return err
+}
+
+const searchServers = `-- name: SearchServers :many
+select s.id, s.ip, s.ip_version, s.user_id, s.account_id, s.hostname, s.stratum, s.in_pool, s.in_server_list, s.netspeed, s.netspeed_target, s.created_on, s.updated_on, s.score_ts, s.score_raw, s.deletion_on, s.flags from servers s
+  inner join accounts a on (a.id=s.account_id)
+where
+  s.in_server_list = 1 AND
+  s.deletion_on IS NULL AND
+  a.public_profile = 1 AND
+  (? IS NULL OR s.id IN (
+    select sz.server_id from server_zones sz
+      inner join zones z on (z.id=sz.zone_id)
+    where z.name = ?)) AND
+  (? IS NULL OR s.ip_version = ?) AND
+  (? IS NULL OR s.score_raw >= ?) AND
+  (? IS NULL OR s.netspeed >= ?) AND
+  (? IS NULL OR s.netspeed <= ?) AND
+  (? IS NULL OR s.hostname like ?) AND
+  (? IS NULL OR a.url_slug = ?) AND
+  (? IS NULL OR
+    (CASE WHEN ? = 'score' THEN s.score_raw
+      WHEN ? = 'netspeed' THEN s.netspeed
+      ELSE 0 END) < ? OR
+    ((CASE WHEN ? = 'score' THEN s.score_raw
+      WHEN ? = 'netspeed' THEN s.netspeed
+      ELSE 0 END) = ? AND s.id > ?))
+order by
+  (CASE WHEN ? = 'score' THEN s.score_raw
+    WHEN ? = 'netspeed' THEN s.netspeed
+    ELSE 0 END) desc,
+  s.id
+limit ?
+`
+
+type SearchServersParams struct {
+	Zone        sql.NullString       `db:"zone" json:"zone"`
+	IpVersion   NullServersIpVersion `db:"ip_version" json:"ip_version"`
+	MinScore    sql.NullFloat64      `db:"min_score" json:"min_score"`
+	MinNetspeed sql.NullInt32        `db:"min_netspeed" json:"min_netspeed"`
+	MaxNetspeed sql.NullInt32        `db:"max_netspeed" json:"max_netspeed"`
+	Hostname    sql.NullString       `db:"hostname" json:"hostname"`
+	Account     sql.NullString       `db:"account" json:"account"`
+	AfterValue  interface{}          `db:"after_value" json:"after_value"`
+	Sort        interface{}          `db:"sort" json:"sort"`
+	AfterID     uint32               `db:"after_id" json:"after_id"`
+	Limit       int32                `db:"limit" json:"limit"`
+}
+
+// Only the servers listed on the public site: in the server list,
+// not scheduled for deletion and in an account with a public
+// profile. The sort is 'id', 'score' or 'netspeed' (highest first);
+// after_value and after_id are the sort value and ID of the last
+// server on the previous page (after_value is 0 for the 'id' sort).
+func (q *Queries) SearchServers(ctx context.Context, arg SearchServersParams) ([]Server, error) {
+	rows, err := q.db.QueryContext(ctx, searchServers,
+		arg.Zone,
+		arg.Zone,
+		arg.IpVersion,
+		arg.IpVersion,
+		arg.MinScore,
+		arg.MinScore,
+		arg.MinNetspeed,
+		arg.MinNetspeed,
+		arg.MaxNetspeed,
+		arg.MaxNetspeed,
+		arg.Hostname,
+		arg.Hostname,
+		arg.Account,
+		arg.Account,
+		arg.AfterValue,
+		arg.Sort,
+		arg.Sort,
+		arg.AfterValue,
+		arg.Sort,
+		arg.Sort,
+		arg.AfterValue,
+		arg.AfterID,
+		arg.Sort,
+		arg.Sort,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Server
+	for rows.Next() {
+		var i Server
+		if err := rows.Scan(
+			&i.ID,
+			&i.Ip,
+			&i.IpVersion,
+			&i.UserID,
+			&i.AccountID,
+			&i.Hostname,
+			&i.Stratum,
+			&i.InPool,
+			&i.InServerList,
+			&i.Netspeed,
+			&i.NetspeedTarget,
+			&i.CreatedOn,
+			&i.UpdatedOn,
+			&i.ScoreTs,
+			&i.ScoreRaw,
+			&i.DeletionOn,
+			&i.Flags,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const updateArchiveStatus = `-- name: UpdateArchiveStatus :exec
+update log_scores_archive_status
+  set log_score_id = ?
+  where archiver = ?
+`
+
+type UpdateArchiveStatusParams struct {
+	LogScoreID sql.NullInt64 `db:"log_score_id" json:"log_score_id"`
+	Archiver   string        `db:"archiver" json:"archiver"`
+}
+
+func (q *Queries) UpdateArchiveStatus(ctx context.Context, arg UpdateArchiveStatusParams) error {
+	_, err := q.db.ExecContext(ctx, updateArchiveStatus, arg.LogScoreID, arg.Archiver)
+	return err
+}
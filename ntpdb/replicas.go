@@ -0,0 +1,356 @@
+package ntpdb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.ntppool.org/common/logger"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const defaultMaxReplicaLag = 30 * time.Second
+
+// Replicas are the read replicas of the MySQL database.
+type Replicas struct {
+	primaryHost string
+	maxLag      time.Duration
+	replicas    []*replica
+}
+
+type replica struct {
+	db   *sql.DB
+	host string
+
+	healthy atomic.Bool
+
+	// the result of the last replication check or ping
+	mu      sync.Mutex
+	lag     time.Duration
+	err     error
+	checked time.Time
+}
+
+// ReplicaStatus is the state of a read replica from the last
+// replication check (or ping) and its connection pool.
+type ReplicaStatus struct {
+	Host    string
+	Healthy bool
+	Lag     time.Duration
+	Error   string
+	Checked time.Time
+	Stats   sql.DBStats
+}
+
+func (rep *replica) setStatus(lag time.Duration, err error) {
+	rep.mu.Lock()
+	defer rep.mu.Unlock()
+	rep.lag = lag
+	rep.err = err
+	rep.checked = time.Now()
+}
+
+func (rep *replica) status() ReplicaStatus {
+	rep.mu.Lock()
+	defer rep.mu.Unlock()
+	st := ReplicaStatus{
+		Host:    rep.host,
+		Healthy: rep.healthy.Load(),
+		Lag:     rep.lag,
+		Checked: rep.checked,
+		Stats:   rep.db.Stats(),
+	}
+	if rep.err != nil {
+		st.Error = rep.err.Error()
+	}
+	return st
+}
+
+// OpenReplicas opens the replicas in the configuration file. They
+// aren't used until the replication status has been checked (see
+// CheckReplicas).
+func OpenReplicas(ctx context.Context, configFile string) (*Replicas, error) {
+	cfg, err := loadConfig(ctx, configFile)
+	if err != nil {
+		return nil, err
+	}
+
+	primary, err := cfg.mysqlConfig(-1)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Replicas{
+		primaryHost: primary.Addr,
+		maxLag:      cfg.MySQL.MaxReplicaLag,
+	}
+	if r.maxLag <= 0 {
+		r.maxLag = defaultMaxReplicaLag
+	}
+
+	for i := range cfg.MySQL.Replicas {
+		dbcfg, err := cfg.mysqlConfig(i)
+		if err != nil {
+			return nil, err
+		}
+
+		db := sql.OpenDB(Driver{CreateConnectorFunc: createConnector(ctx, configFile, i)})
+		db.SetConnMaxLifetime(time.Minute * 3)
+		db.SetMaxOpenConns(8)
+		db.SetMaxIdleConns(3)
+
+		r.replicas = append(r.replicas, &replica{db: db, host: dbcfg.Addr})
+	}
+
+	return r, nil
+}
+
+// Status returns the state of each replica.
+func (r *Replicas) Status() []ReplicaStatus {
+	rv := make([]ReplicaStatus, 0, len(r.replicas))
+	for _, rep := range r.replicas {
+		rv = append(rv, rep.status())
+	}
+	return rv
+}
+
+// Ping pings the replicas; one that doesn't respond isn't used
+// until the next replication check finds it working. The error has
+// the replicas that failed.
+func (r *Replicas) Ping(ctx context.Context) error {
+	errs := make([]error, len(r.replicas))
+
+	var wg sync.WaitGroup
+	for i, rep := range r.replicas {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := rep.db.PingContext(ctx)
+			if err == nil {
+				return
+			}
+			if rep.healthy.Swap(false) {
+				logger.FromContext(ctx).WarnContext(ctx, "replica unavailable", "replica", rep.host, "err", err)
+			}
+			rep.setStatus(0, err)
+			errs[i] = fmt.Errorf("replica %s: %w", rep.host, err)
+		}()
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+// replicatedDB sends the queries to a healthy replica first and to
+// the primary if there are none or the query on the replica fails.
+// Statements that aren't queries only go to the primary.
+type replicatedDB struct {
+	primary  *sql.DB
+	replicas *Replicas
+	next     atomic.Uint32
+}
+
+// NewReplicatedDB returns a DB running the queries (with tracing) on
+// the replicas, falling back to the primary database.
+func NewReplicatedDB(primary *sql.DB, replicas *Replicas) DB {
+	if replicas == nil || len(replicas.replicas) == 0 {
+		return NewDB(primary)
+	}
+	rdb := &replicatedDB{primary: primary, replicas: replicas}
+	return &sqlDB{
+		QuerierTx: NewWrappedQuerier(New(rdb)),
+		db:        primary,
+		replicas:  replicas,
+	}
+}
+
+// healthy returns the healthy replicas, starting with a different
+// one for each query.
+func (d *replicatedDB) healthy() []*replica {
+	rs := d.replicas.replicas
+	start := int(d.next.Add(1))
+	healthy := []*replica{}
+	for i := range rs {
+		r := rs[(start+i)%len(rs)]
+		if r.healthy.Load() {
+			healthy = append(healthy, r)
+		}
+	}
+	return healthy
+}
+
+// fallback returns true if a query that failed on a replica should be
+// retried on the primary.
+func fallback(ctx context.Context, r *replica, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	log := logger.FromContext(ctx)
+	log.WarnContext(ctx, "replica query failed, using the primary", "replica", r.host, "err", err)
+	trace.SpanFromContext(ctx).AddEvent("replica query failed",
+		trace.WithAttributes(
+			attribute.String("db.replica", r.host),
+			attribute.String("error", err.Error()),
+		),
+	)
+	return true
+}
+
+func setServerAddress(ctx context.Context, host string, replica bool) {
+	trace.SpanFromContext(ctx).SetAttributes(
+		attribute.String("server.address", host),
+		attribute.Bool("db.replica", replica),
+	)
+}
+
+func (d *replicatedDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	for _, r := range d.healthy() {
+		rows, err := r.db.QueryContext(ctx, query, args...)
+		if err == nil {
+			setServerAddress(ctx, r.host, true)
+			return rows, nil
+		}
+		if !fallback(ctx, r, err) {
+			return nil, err
+		}
+	}
+	setServerAddress(ctx, d.replicas.primaryHost, false)
+	return d.primary.QueryContext(ctx, query, args...)
+}
+
+func (d *replicatedDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	for _, r := range d.healthy() {
+		row := r.db.QueryRowContext(ctx, query, args...)
+		// Err doesn't return sql.ErrNoRows, only errors running
+		// the query
+		err := row.Err()
+		if err == nil {
+			setServerAddress(ctx, r.host, true)
+			return row
+		}
+		if !fallback(ctx, r, err) {
+			return row
+		}
+	}
+	setServerAddress(ctx, d.replicas.primaryHost, false)
+	return d.primary.QueryRowContext(ctx, query, args...)
+}
+
+func (d *replicatedDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	setServerAddress(ctx, d.replicas.primaryHost, false)
+	return d.primary.ExecContext(ctx, query, args...)
+}
+
+func (d *replicatedDB) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	setServerAddress(ctx, d.replicas.primaryHost, false)
+	return d.primary.PrepareContext(ctx, query)
+}
+
+// CheckReplicas checks the replication status of the replicas every
+// interval until the context is done. A replica is used if the
+// replication is running and the lag is less than the configured
+// maximum.
+func (r *Replicas) CheckReplicas(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		for _, rep := range r.replicas {
+			r.check(ctx, rep)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *Replicas) check(ctx context.Context, rep *replica) {
+	log := logger.FromContext(ctx).With("replica", rep.host)
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	lag, err := replicaLag(ctx, rep.db)
+	if err == nil && lag > r.maxLag {
+		rep.setStatus(lag, fmt.Errorf("replication lag %s over %s", lag, r.maxLag))
+	} else {
+		rep.setStatus(lag, err)
+	}
+	if err != nil {
+		if rep.healthy.Swap(false) {
+			log.WarnContext(ctx, "replica unavailable", "err", err)
+		}
+		return
+	}
+
+	healthy := lag <= r.maxLag
+	if was := rep.healthy.Swap(healthy); was != healthy {
+		if healthy {
+			log.InfoContext(ctx, "replica available", "lag", lag)
+		} else {
+			log.WarnContext(ctx, "replica lagging", "lag", lag, "max_lag", r.maxLag)
+		}
+	}
+}
+
+// replicaLag returns how far the replica is behind the primary. It's
+// an error if replication isn't running.
+func replicaLag(ctx context.Context, db *sql.DB) (time.Duration, error) {
+	// SHOW REPLICA STATUS is in MySQL 8.0.22 and MariaDB 10.5.1
+	// and later
+	rows, err := db.QueryContext(ctx, "SHOW REPLICA STATUS")
+	if err != nil {
+		rows, err = db.QueryContext(ctx, "SHOW SLAVE STATUS")
+		if err != nil {
+			return 0, err
+		}
+	}
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return 0, err
+	}
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return 0, err
+		}
+		return 0, errors.New("not a replica")
+	}
+
+	values := make([]sql.NullString, len(cols))
+	dest := make([]any, len(cols))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	err = rows.Scan(dest...)
+	if err != nil {
+		return 0, err
+	}
+
+	for i, col := range cols {
+		if col != "Seconds_Behind_Source" && col != "Seconds_Behind_Master" {
+			continue
+		}
+		if !values[i].Valid {
+			return 0, errors.New("replication not running")
+		}
+		seconds, err := strconv.ParseInt(values[i].String, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("%s: %w", col, err)
+		}
+		return time.Duration(seconds) * time.Second, nil
+	}
+
+	return 0, errors.New("replication delay not in replica status")
+}
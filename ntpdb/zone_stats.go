@@ -2,6 +2,8 @@ package ntpdb
 
 import (
 	"context"
+	"sort"
+	"time"
 
 	"go.ntppool.org/common/logger"
 	"go.ntppool.org/common/tracing"
@@ -13,10 +15,13 @@ type ZoneStat struct {
 	CC string
 	V4 float64
 	V6 float64
+
+	// ActiveV4 and ActiveV6 are the number of active servers
+	ActiveV4 uint32
+	ActiveV6 uint32
 }
 
 func GetZoneStats(ctx context.Context, q Querier) (*ZoneStats, error) {
-	log := logger.Setup()
 	ctx, span := tracing.Tracer().Start(ctx, "GetZoneStats")
 	defer span.End()
 
@@ -25,6 +30,56 @@ func GetZoneStats(ctx context.Context, q Querier) (*ZoneStats, error) {
 		return nil, err
 	}
 
+	data := zoneStats(ctx, zoneStatsRows)
+
+	return &data, nil
+}
+
+// ZoneStatsDay is the netspeed share of the zones on one day; Date
+// is formatted as YYYY-MM-DD.
+type ZoneStatsDay struct {
+	Date      string
+	ZoneStats ZoneStats
+}
+
+// GetZoneStatsSeries returns the netspeed share of the zones for each
+// day with zone server counts from 'from' up to and including 'to'.
+func GetZoneStatsSeries(ctx context.Context, q Querier, from, to time.Time) ([]ZoneStatsDay, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "GetZoneStatsSeries")
+	defer span.End()
+
+	historyRows, err := q.GetZoneStatsHistory(ctx, GetZoneStatsHistoryParams{
+		FromDate: from,
+		ToDate:   to,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	rv := []ZoneStatsDay{}
+
+	// the rows are ordered by date
+	var dayRows []GetZoneStatsDataRow
+	for i, r := range historyRows {
+		dayRows = append(dayRows, GetZoneStatsDataRow(r))
+		if i+1 < len(historyRows) && historyRows[i+1].Date.Equal(r.Date) {
+			continue
+		}
+		rv = append(rv, ZoneStatsDay{
+			Date:      r.Date.Format(time.DateOnly),
+			ZoneStats: zoneStats(ctx, dayRows),
+		})
+		dayRows = nil
+	}
+
+	return rv, nil
+}
+
+// zoneStats returns the netspeed share of each zone in the zone
+// server counts for one day.
+func zoneStats(ctx context.Context, zoneStatsRows []GetZoneStatsDataRow) ZoneStats {
+	log := logger.Setup()
+
 	var (
 		total4 float64
 		total6 float64
@@ -36,6 +91,8 @@ func GetZoneStats(ctx context.Context, q Querier) (*ZoneStats, error) {
 	type counts struct {
 		Netspeed4    uint64
 		Netspeed6    uint64
+		Active4      uint32
+		Active6      uint32
 		PercentTotal struct {
 			V4 float64
 			V6 float64
@@ -64,8 +121,10 @@ func GetZoneStats(ctx context.Context, q Querier) (*ZoneStats, error) {
 		switch r.IpVersion {
 		case "v4":
 			c.Netspeed4 = uint64(r.NetspeedActive)
+			c.Active4 = r.CountActive
 		case "v6":
 			c.Netspeed6 = uint64(r.NetspeedActive)
+			c.Active6 = r.CountActive
 		}
 
 	}
@@ -73,17 +132,25 @@ func GetZoneStats(ctx context.Context, q Querier) (*ZoneStats, error) {
 	data := ZoneStats{}
 	for name, cc := range ccs {
 
-		log.InfoContext(ctx, "zone stats cc", "name", name)
+		log.DebugContext(ctx, "zone stats cc", "name", name)
 
-		cc.PercentTotal.V4 = (100 / total4) * float64(cc.Netspeed4)
-		cc.PercentTotal.V6 = (100 / total6) * float64(cc.Netspeed6)
+		if total4 > 0 {
+			cc.PercentTotal.V4 = (100 / total4) * float64(cc.Netspeed4)
+		}
+		if total6 > 0 {
+			cc.PercentTotal.V6 = (100 / total6) * float64(cc.Netspeed6)
+		}
 
 		data = append(data, ZoneStat{
-			CC: name,
-			V4: cc.PercentTotal.V4,
-			V6: cc.PercentTotal.V6,
+			CC:       name,
+			V4:       cc.PercentTotal.V4,
+			V6:       cc.PercentTotal.V6,
+			ActiveV4: cc.Active4,
+			ActiveV6: cc.Active6,
 		})
 	}
 
-	return &data, nil
+	sort.Slice(data, func(i, j int) bool { return data[i].CC < data[j].CC })
+
+	return data
 }
@@ -6,6 +6,13 @@ FROM zone_server_counts zc USE INDEX (date_idx)
   WHERE date IN (SELECT max(date) from zone_server_counts)
 ORDER BY name;
 
+-- name: GetZoneStatsHistory :many
+SELECT zc.date, z.name, zc.ip_version, count_active, count_registered, netspeed_active
+FROM zone_server_counts zc USE INDEX (date_idx)
+  INNER JOIN zones z
+    ON(zc.zone_id=z.id)
+  WHERE date >= sqlc.arg(from_date) AND date <= sqlc.arg(to_date)
+ORDER BY date, name;
 
 -- name: GetServerNetspeed :one
 select netspeed from servers where ip = ?;
@@ -72,20 +79,25 @@ where
   server_id = ? AND
   monitor_id in (sqlc.slice('MonitorIDs'));
 
--- name: GetServerLogScores :many
-select * from log_scores
+-- name: GetServerLogScoresByTime :many
+select * from log_scores USE INDEX (log_scores_server_ts_idx)
 where
-  server_id = ?
-  order by ts desc
-  limit ?;
+  server_id = sqlc.arg(server_id) AND
+  ts > sqlc.arg(since) AND
+  ts <= sqlc.arg(until) AND
+  (sqlc.narg(monitor_id) IS NULL OR monitor_id = sqlc.narg(monitor_id))
+  order by ts
+  limit sqlc.arg('limit');
 
--- name: GetServerLogScoresByMonitorID :many
-select * from log_scores
+-- name: GetServerLogScoresByTimeDesc :many
+select * from log_scores USE INDEX (log_scores_server_ts_idx)
 where
-  server_id = ? AND
-  monitor_id = ?
+  server_id = sqlc.arg(server_id) AND
+  ts > sqlc.arg(since) AND
+  ts <= sqlc.arg(until) AND
+  (sqlc.narg(monitor_id) IS NULL OR monitor_id = sqlc.narg(monitor_id))
   order by ts desc
-  limit ?;
+  limit sqlc.arg('limit');
 
 -- name: GetZoneByName :one
 select * from zones
@@ -96,3 +108,166 @@ where
 select * from zone_server_counts
   where zone_id = ?
   order by date;
+
+-- name: GetArchiveStatus :one
+select * from log_scores_archive_status
+where
+  archiver = ?
+  for update;
+
+-- name: InsertArchiveStatus :exec
+insert into log_scores_archive_status
+  (archiver, log_score_id)
+  values (?, NULL);
+
+-- name: UpdateArchiveStatus :exec
+update log_scores_archive_status
+  set log_score_id = ?
+  where archiver = ?;
+
+-- name: GetLogScoresAfterID :many
+select * from log_scores
+where
+  id > ?
+  order by id
+  limit ?;
+
+-- name: GetServerZoneNetspeedHistory :many
+select z.name as zone_name, zc.date, zc.netspeed_active
+from zone_server_counts zc
+  inner join zones z on (z.id=zc.zone_id)
+  inner join server_zones sz on (sz.zone_id=zc.zone_id)
+  inner join servers s on (s.id=sz.server_id AND s.ip_version=zc.ip_version)
+where
+  s.ip = sqlc.arg(server_ip) AND
+  zc.date >= sqlc.arg(from_date) AND
+  zc.date <= sqlc.arg(to_date)
+order by zc.date, z.name;
+
+-- name: GetServersByHostname :many
+select s.* from servers s
+  inner join accounts a on (a.id=s.account_id)
+where
+  s.hostname = sqlc.arg(hostname) AND
+  a.public_profile = 1 AND
+  (s.deletion_on IS NULL OR s.deletion_on > NOW())
+order by s.ip_version, s.ip;
+
+-- name: GetServersByAccount :many
+select s.* from servers s
+  inner join accounts a on (a.id=s.account_id)
+where
+  (a.id_token = sqlc.arg(account) OR a.url_slug = sqlc.arg(account)) AND
+  a.public_profile = 1 AND
+  (s.deletion_on IS NULL OR s.deletion_on > NOW())
+order by s.ip_version, s.ip;
+
+-- name: GetVendorZone :one
+select vz.id, vz.zone_name, vz.client_type, dr.origin
+from vendor_zones vz
+  inner join dns_roots dr on (dr.id=vz.dns_root_id)
+where
+  vz.zone_name = sqlc.arg(zone_name) AND
+  dr.origin = sqlc.arg(origin) AND
+  vz.status = 'Approved';
+
+-- name: GetDNSRoots :many
+select * from dns_roots
+order by origin;
+
+-- name: GetLatestLogScoreTs :one
+select ts from log_scores
+  order by id desc
+  limit 1;
+
+-- name: GetLatestZoneCountsDate :one
+select date from zone_server_counts
+  order by date desc
+  limit 1;
+
+-- name: GetLatestLogScoreID :one
+select id from log_scores
+  order by id desc
+  limit 1;
+
+-- name: GetScorers :many
+select m.id, m.hostname, m.status,
+  ss.log_score_id, ss.modified_on, ls.ts as log_score_ts
+from monitors m
+  left join scorer_status ss on (ss.scorer_id=m.id)
+  left join log_scores ls on (ls.id=ss.log_score_id)
+where
+  m.type = 'score' AND
+  m.deleted_on IS NULL
+order by m.hostname, m.id;
+
+-- name: GetServerVerification :one
+select verified_on, created_on
+from server_verifications
+where server_id = ?;
+
+-- name: GetServerVerificationHistory :many
+select id, verified_on, created_on
+from server_verifications_history
+where server_id = ?
+order by created_on desc, id desc
+limit 100;
+
+-- name: GetServerZones :many
+select z.name from zones z
+  inner join server_zones sz on (sz.zone_id=z.id)
+where sz.server_id = ?
+order by z.name;
+
+-- name: GetServerURLs :many
+select url from server_urls
+where server_id = ?
+order by id;
+
+-- name: GetPublicAccount :one
+select id_token, name, organization_name, organization_url, url_slug
+from accounts
+where
+  id = ? AND
+  public_profile = 1;
+
+-- name: GetServerMonitorReview :one
+select last_review, next_review
+from servers_monitor_review
+where server_id = ?;
+
+-- name: SearchServers :many
+-- Only the servers listed on the public site: in the server list,
+-- not scheduled for deletion and in an account with a public
+-- profile. The sort is 'id', 'score' or 'netspeed' (highest first);
+-- after_value and after_id are the sort value and ID of the last
+-- server on the previous page (after_value is 0 for the 'id' sort).
+select s.* from servers s
+  inner join accounts a on (a.id=s.account_id)
+where
+  s.in_server_list = 1 AND
+  s.deletion_on IS NULL AND
+  a.public_profile = 1 AND
+  (sqlc.narg(zone) IS NULL OR s.id IN (
+    select sz.server_id from server_zones sz
+      inner join zones z on (z.id=sz.zone_id)
+    where z.name = sqlc.narg(zone))) AND
+  (sqlc.narg(ip_version) IS NULL OR s.ip_version = sqlc.narg(ip_version)) AND
+  (sqlc.narg(min_score) IS NULL OR s.score_raw >= sqlc.narg(min_score)) AND
+  (sqlc.narg(min_netspeed) IS NULL OR s.netspeed >= sqlc.narg(min_netspeed)) AND
+  (sqlc.narg(max_netspeed) IS NULL OR s.netspeed <= sqlc.narg(max_netspeed)) AND
+  (sqlc.narg(hostname) IS NULL OR s.hostname like sqlc.narg(hostname)) AND
+  (sqlc.narg(account) IS NULL OR a.url_slug = sqlc.narg(account)) AND
+  (sqlc.narg(after_value) IS NULL OR
+    (CASE WHEN sqlc.arg(sort) = 'score' THEN s.score_raw
+      WHEN sqlc.arg(sort) = 'netspeed' THEN s.netspeed
+      ELSE 0 END) < sqlc.narg(after_value) OR
+    ((CASE WHEN sqlc.arg(sort) = 'score' THEN s.score_raw
+      WHEN sqlc.arg(sort) = 'netspeed' THEN s.netspeed
+      ELSE 0 END) = sqlc.narg(after_value) AND s.id > sqlc.arg(after_id)))
+order by
+  (CASE WHEN sqlc.arg(sort) = 'score' THEN s.score_raw
+    WHEN sqlc.arg(sort) = 'netspeed' THEN s.netspeed
+    ELSE 0 END) desc,
+  s.id
+limit sqlc.arg('limit');
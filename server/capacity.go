@@ -0,0 +1,157 @@
+package server
+
+import (
+	"net/http"
+	"sort"
+
+	"github.com/labstack/echo/v4"
+	"golang.org/x/sync/errgroup"
+
+	"go.ntppool.org/common/logger"
+	"go.ntppool.org/common/tracing"
+	chdb "go.ntppool.org/data-api/chdb"
+	"go.ntppool.org/data-api/ntpdb"
+)
+
+// countryCapacity is the share of the DNS queries (Demand) and of the
+// zone netspeed (Capacity) for a country and IP version, in percent.
+type countryCapacity struct {
+	CC        string
+	IPVersion string
+	Demand    float64
+	Capacity  float64
+	// Ratio is Demand divided by Capacity; it's not set if the
+	// country has no capacity
+	Ratio   *float64 `json:",omitempty"`
+	Servers uint32
+}
+
+// capacity joins the user country DNS queries with the zone netspeed
+// by country and IP version, the most under-served first.
+func (srv *Server) capacity(c echo.Context) error {
+	log := logger.Setup()
+	ctx, span := tracing.Tracer().Start(c.Request().Context(), "capacity")
+	defer span.End()
+
+	if len(c.QueryString()) > 0 {
+		// better URLs are forever
+		c.Response().Header().Set("Cache-Control", "public,max-age=10400")
+		return c.Redirect(http.StatusPermanentRedirect, "https://www.ntppool.org/api/data/usercc/capacity")
+	}
+
+	queryGroup, ctx := errgroup.WithContext(ctx)
+
+	var zoneStats *ntpdb.ZoneStats
+
+	queryGroup.Go(func() error {
+		var err error
+		zoneStats, err = ntpdb.GetZoneStats(ctx, srv.db)
+		if err != nil {
+			log.ErrorContext(ctx, "GetZoneStats", "err", err)
+		}
+		return err
+	})
+
+	var userCountry *chdb.UserCountry
+
+	queryGroup.Go(func() error {
+		var err error
+		userCountry, err = srv.ch.UserCountryData(ctx)
+		if err != nil {
+			log.ErrorContext(ctx, "UserCountryData", "err", err)
+		}
+		return err
+	})
+
+	err := queryGroup.Wait()
+	if err != nil {
+		c.Response().Header().Set("Cache-Control", "public,max-age=300")
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+
+	rv := joinCapacity(userCountry, zoneStats)
+
+	c.Response().Header().Set("Cache-Control", "public,max-age=1800")
+
+	return c.JSONPretty(http.StatusOK, rv, "")
+}
+
+// joinCapacity returns the demand and capacity for each country
+// and IP version with either, sorted by the ratio of demand to
+// capacity; countries with demand and no capacity are first.
+func joinCapacity(userCountry *chdb.UserCountry, zoneStats *ntpdb.ZoneStats) []countryCapacity {
+	type zoneKey struct {
+		cc        string
+		ipVersion string
+	}
+
+	data := map[zoneKey]*countryCapacity{}
+	get := func(cc, ipVersion string) *countryCapacity {
+		if cc == "gb" {
+			// the zone for the United Kingdom is "uk"
+			cc = "uk"
+		}
+		k := zoneKey{cc, ipVersion}
+		if _, ok := data[k]; !ok {
+			data[k] = &countryCapacity{CC: cc, IPVersion: ipVersion}
+		}
+		return data[k]
+	}
+
+	if userCountry != nil {
+		for _, uc := range *userCountry {
+			if len(uc.CC) != 2 {
+				continue
+			}
+			get(uc.CC, "v4").Demand += uc.IPv4
+			get(uc.CC, "v6").Demand += uc.IPv6
+		}
+	}
+
+	if zoneStats != nil {
+		for _, zs := range *zoneStats {
+			// skip the global and continent zones
+			if len(zs.CC) != 2 {
+				continue
+			}
+			v4 := get(zs.CC, "v4")
+			v4.Capacity = zs.V4
+			v4.Servers = zs.ActiveV4
+
+			v6 := get(zs.CC, "v6")
+			v6.Capacity = zs.V6
+			v6.Servers = zs.ActiveV6
+		}
+	}
+
+	rv := []countryCapacity{}
+	for _, cc := range data {
+		if cc.Demand == 0 && cc.Capacity == 0 {
+			continue
+		}
+		if cc.Capacity > 0 {
+			ratio := cc.Demand / cc.Capacity
+			cc.Ratio = &ratio
+		}
+		rv = append(rv, *cc)
+	}
+
+	sort.Slice(rv, func(i, j int) bool {
+		a, b := rv[i], rv[j]
+		if (a.Ratio == nil) != (b.Ratio == nil) {
+			return a.Ratio == nil
+		}
+		if a.Ratio != nil && *a.Ratio != *b.Ratio {
+			return *a.Ratio > *b.Ratio
+		}
+		if a.Demand != b.Demand {
+			return a.Demand > b.Demand
+		}
+		if a.CC != b.CC {
+			return a.CC < b.CC
+		}
+		return a.IPVersion < b.IPVersion
+	})
+
+	return rv
+}
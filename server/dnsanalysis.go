@@ -0,0 +1,159 @@
+package server
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/netip"
+	"slices"
+
+	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/otel/attribute"
+
+	"go.ntppool.org/common/logger"
+	"go.ntppool.org/common/tracing"
+)
+
+const (
+	// a zone is flagged when the share of DNS answers is this much
+	// over or under the share of the zone netspeed
+	answerShareOverRatio  = 1.5
+	answerShareUnderRatio = 1 / answerShareOverRatio
+
+	// zones where the netspeed share would give fewer answers
+	// aren't flagged
+	answerShareMinCount = 100
+)
+
+type answerShareStatus string
+
+const (
+	answerShareOK      answerShareStatus = "ok"
+	answerShareOver    answerShareStatus = "over"
+	answerShareUnder   answerShareStatus = "under"
+	answerShareLowData answerShareStatus = "low_data"
+	answerShareNoZone  answerShareStatus = "no_zone"
+)
+
+// dnsAnalysis compares the share of the DNS answers the server got
+// in each country zone with the share of the zone netspeed it has.
+func (srv *Server) dnsAnalysis(c echo.Context) error {
+	log := logger.Setup()
+	ctx, span := tracing.Tracer().Start(c.Request().Context(), "dnsanalysis")
+	defer span.End()
+
+	// for errors and 404s, a shorter cache time
+	c.Response().Header().Set("Cache-Control", "public,max-age=300")
+
+	log = log.With("server_param", c.Param("server"))
+	span.SetAttributes(attribute.String("server_param", c.Param("server")))
+
+	ip, err := netip.ParseAddr(c.Param("server"))
+	if err != nil {
+		log.Warn("could not parse server parameter", "server", c.Param("server"), "err", err)
+		return c.NoContent(http.StatusBadRequest)
+	}
+
+	if ip.String() != c.Param("server") || len(c.QueryString()) > 0 {
+		// better URLs are forever
+		c.Response().Header().Set("Cache-Control", "public,max-age=10400")
+		return c.Redirect(http.StatusPermanentRedirect, "https://www.ntppool.org/api/data/server/dns/analysis/"+ip.String())
+	}
+
+	serverData, totalData, zoneNetspeed, err := srv.serverAnswers(ctx, ip, 3)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.String(http.StatusNotFound, "Not found")
+		}
+		log.Error("query error", "err", err)
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+
+	type zoneShare struct {
+		CC       string
+		Count    uint64
+		Points   float64
+		Netspeed float64
+		// Ratio is the answer share divided by the netspeed share
+		Ratio  *float64 `json:",omitempty"`
+		Status answerShareStatus
+	}
+
+	r := struct {
+		Zones []zoneShare
+		// EffectiveWeight is the answers the server got divided by the
+		// answers its netspeed share of each zone would give
+		EffectiveWeight *float64 `json:",omitempty"`
+		PointSymbol     string
+	}{
+		Zones:       []zoneShare{},
+		PointSymbol: pointSymbol,
+	}
+
+	// the zones the server got answers in, and then the rest of its
+	// country zones; the continent zones don't have answer totals
+	zones := []zoneShare{}
+	seen := map[string]bool{}
+	for _, cc := range serverData {
+		if cc.CC == "" {
+			// the total over all countries
+			continue
+		}
+		seen[cc.CC] = true
+		zones = append(zones, zoneShare{
+			CC:       cc.CC,
+			Count:    cc.Count,
+			Points:   cc.Points,
+			Netspeed: cc.Netspeed,
+		})
+	}
+	missing := []string{}
+	for cc := range zoneNetspeed {
+		if _, ok := totalData[cc]; ok && cc != "" && !seen[cc] {
+			missing = append(missing, cc)
+		}
+	}
+	slices.Sort(missing)
+	for _, cc := range missing {
+		zones = append(zones, zoneShare{CC: cc, Netspeed: zoneNetspeed[cc]})
+	}
+
+	var answers, expected float64
+
+	for _, zs := range zones {
+		if zs.Netspeed == 0 {
+			zs.Status = answerShareNoZone
+		} else {
+			ratio := zs.Points / zs.Netspeed
+			zs.Ratio = &ratio
+
+			// the answers the netspeed share of the zone would give
+			zoneExpected := zs.Netspeed / pointBasis * float64(totalData[zs.CC])
+
+			answers += float64(zs.Count)
+			expected += zoneExpected
+
+			switch {
+			case zoneExpected < answerShareMinCount:
+				zs.Status = answerShareLowData
+			case ratio > answerShareOverRatio:
+				zs.Status = answerShareOver
+			case ratio < answerShareUnderRatio:
+				zs.Status = answerShareUnder
+			default:
+				zs.Status = answerShareOK
+			}
+		}
+
+		r.Zones = append(r.Zones, zs)
+	}
+
+	if expected > 0 {
+		weight := answers / expected
+		r.EffectiveWeight = &weight
+	}
+
+	c.Response().Header().Set("Cache-Control", "public,max-age=1800")
+
+	return c.JSONPretty(http.StatusOK, r, "")
+}
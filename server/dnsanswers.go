@@ -1,10 +1,13 @@
 package server
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"net/http"
 	"net/netip"
+	"net/url"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"go.opentelemetry.io/otel/attribute"
@@ -45,12 +48,60 @@ func (srv *Server) dnsAnswers(c echo.Context) error {
 		return c.NoContent(http.StatusBadRequest)
 	}
 
-	if ip.String() != c.Param("server") || len(c.QueryString()) > 0 {
+	// only the time range parameters are used
+	query := url.Values{}
+	for _, k := range []string{"from", "to"} {
+		if v := c.QueryParam(k); len(v) > 0 {
+			query.Set(k, v)
+		}
+	}
+
+	if ip.String() != c.Param("server") || c.QueryString() != query.Encode() {
 		// better URLs are forever
+		u := "https://www.ntppool.org/api/data/server/dns/answers/" + ip.String()
+		if len(query) > 0 {
+			u += "?" + query.Encode()
+		}
 		c.Response().Header().Set("Cache-Control", "public,max-age=10400")
-		return c.Redirect(http.StatusPermanentRedirect, "https://www.ntppool.org/api/data/server/dns/answers/"+ip.String())
+		return c.Redirect(http.StatusPermanentRedirect, u)
 	}
 
+	if len(query) > 0 {
+		return srv.dnsAnswersSeries(ctx, c, ip)
+	}
+
+	serverData, _, _, err := srv.serverAnswers(ctx, ip, 3)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.String(http.StatusNotFound, "Not found")
+		}
+		log.Error("query error", "err", err)
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+
+	r := struct {
+		Server interface{}
+		// Totals interface{}
+		PointSymbol string
+	}{
+		Server:      serverData,
+		PointSymbol: pointSymbol,
+		// Totals: totalData,
+	}
+
+	c.Response().Header().Set("Cache-Control", "public,max-age=1800")
+
+	return c.JSONPretty(http.StatusOK, r, "")
+}
+
+// serverAnswers returns the DNS answers for the server by UserCC in
+// the last days with the points and netspeed share set, the answer
+// totals for the server's query type and the server's netspeed share
+// of each of its zones by country code. sql.ErrNoRows is returned if
+// the server doesn't exist.
+func (srv *Server) serverAnswers(ctx context.Context, ip netip.Addr, days int) (chdb.ServerQueries, chdb.ServerTotals, map[string]float64, error) {
+	log := logger.FromContext(ctx).With("server", ip.String())
+
 	queryGroup, ctx := errgroup.WithContext(ctx)
 
 	var zoneStats []ntpdb.GetZoneStatsV2Row
@@ -58,7 +109,7 @@ func (srv *Server) dnsAnswers(c echo.Context) error {
 
 	queryGroup.Go(func() error {
 		var err error
-		q := ntpdb.NewWrappedQuerier(ntpdb.New(srv.db))
+		q := srv.db
 
 		serverNetspeed, err = q.GetServerNetspeed(ctx, ip.String())
 		if err != nil {
@@ -81,8 +132,6 @@ func (srv *Server) dnsAnswers(c echo.Context) error {
 		return nil
 	})
 
-	days := 3
-
 	var serverData chdb.ServerQueries
 
 	queryGroup.Go(func() error {
@@ -112,6 +161,100 @@ func (srv *Server) dnsAnswers(c echo.Context) error {
 		return err
 	})
 
+	err := queryGroup.Wait()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	zoneTotals := map[string]int32{}
+	zoneNetspeed := map[string]float64{}
+
+	for _, z := range zoneStats {
+		zoneTotals[zoneTotalName(z.ZoneName)] = z.NetspeedActive // binary.BigEndian.Uint64(...)
+		// log.Info("zone netspeed", "cc", z.ZoneName, "speed", z.NetspeedActive)
+
+		cc := zoneTotalName(z.ZoneName)
+		if cc == "uk" {
+			cc = "gb"
+		}
+		zoneNetspeed[cc] = netspeedPoints(z.NetspeedActive, serverNetspeed)
+	}
+
+	setAnswerPoints(serverData, totalData, zoneTotals, serverNetspeed)
+
+	return serverData, totalData, zoneNetspeed, nil
+}
+
+// dnsAnswersSeries returns the DNS answers for the server by day
+// with the points and netspeed share calculated for each day.
+func (srv *Server) dnsAnswersSeries(ctx context.Context, c echo.Context, ip netip.Addr) error {
+	log := logger.FromContext(ctx)
+	ctx, span := tracing.Tracer().Start(ctx, "dnsanswers.series")
+	defer span.End()
+
+	from, to, err := parseDateRange(c, 30, 180)
+	if err != nil {
+		return err
+	}
+
+	queryGroup, ctx := errgroup.WithContext(ctx)
+
+	var zoneHistory []ntpdb.GetServerZoneNetspeedHistoryRow
+	var serverNetspeed uint32
+
+	queryGroup.Go(func() error {
+		var err error
+		q := srv.db
+
+		serverNetspeed, err = q.GetServerNetspeed(ctx, ip.String())
+		if err != nil {
+			if !errors.Is(err, sql.ErrNoRows) {
+				log.Error("GetServerNetspeed", "err", err)
+			}
+			return err // this will return if the server doesn't exist
+		}
+
+		zoneHistory, err = q.GetServerZoneNetspeedHistory(ctx, ntpdb.GetServerZoneNetspeedHistoryParams{
+			ServerIP: ip.String(),
+			FromDate: from,
+			ToDate:   to,
+		})
+		if err != nil {
+			log.Error("GetServerZoneNetspeedHistory", "err", err)
+			return err
+		}
+
+		return nil
+	})
+
+	var serverData []chdb.ServerQueriesDay
+
+	queryGroup.Go(func() error {
+		var err error
+		serverData, err = srv.ch.ServerAnswerSeries(ctx, ip.String(), from, to)
+		if err != nil {
+			log.Error("ServerAnswerSeries", "err", err)
+		}
+		return err
+	})
+
+	var totalData map[string]chdb.ServerTotals
+
+	queryGroup.Go(func() error {
+		var err error
+
+		qtype := "A"
+		if ip.Is6() {
+			qtype = "AAAA"
+		}
+
+		totalData, err = srv.ch.AnswerTotalsSeries(ctx, qtype, from, to)
+		if err != nil {
+			log.Error("AnswerTotalsSeries", "err", err)
+		}
+		return err
+	})
+
 	err = queryGroup.Wait()
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -121,47 +264,77 @@ func (srv *Server) dnsAnswers(c echo.Context) error {
 		return c.String(http.StatusInternalServerError, err.Error())
 	}
 
-	zoneTotals := map[string]int32{}
+	// zone netspeed by date and zone name
+	zoneTotals := map[string]map[string]int32{}
 
-	for _, z := range zoneStats {
-		zn := z.ZoneName
-		if zn == "@" {
-			zn = ""
+	for _, z := range zoneHistory {
+		date := z.Date.Format(time.DateOnly)
+		if zoneTotals[date] == nil {
+			zoneTotals[date] = map[string]int32{}
 		}
-		zoneTotals[zn] = z.NetspeedActive // binary.BigEndian.Uint64(...)
-		// log.Info("zone netspeed", "cc", z.ZoneName, "speed", z.NetspeedActive)
+		zoneTotals[date][zoneTotalName(z.ZoneName)] = int32(z.NetspeedActive)
 	}
 
-	for _, cc := range serverData {
-		cc.Points = (pointBasis / float64(totalData[cc.CC])) * float64(cc.Count)
-		totalName := cc.CC
-		if totalName == "gb" {
-			totalName = "uk"
-		}
-		if zt, ok := zoneTotals[totalName]; ok {
-			// log.InfoContext(ctx, "netspeed data", "pointBasis", pointBasis, "zt", zt, "server netspeed", serverNetspeed)
-			if zt == 0 {
-				// if the recorded netspeed for the zone was zero, assume it's at least
-				// this servers worth instead. Otherwise the Netspeed gets to be 'infinite'.
-				zt = int32(serverNetspeed)
-			}
-			cc.Netspeed = (pointBasis / float64(zt)) * float64(serverNetspeed)
-		}
-		// log.DebugContext(ctx, "points", "cc", cc.CC, "points", cc.Points)
+	for _, day := range serverData {
+		setAnswerPoints(day.Server, totalData[day.Date], zoneTotals[day.Date], serverNetspeed)
 	}
 
 	r := struct {
-		Server interface{}
-		// Totals interface{}
+		From        string
+		To          string
+		Series      []chdb.ServerQueriesDay
 		PointSymbol string
 	}{
-		Server:      serverData,
+		From:        from.Format(time.DateOnly),
+		To:          to.Format(time.DateOnly),
+		Series:      serverData,
 		PointSymbol: pointSymbol,
-		// Totals: totalData,
 	}
 
 	c.Response().Header().Set("Cache-Control", "public,max-age=1800")
 
 	return c.JSONPretty(http.StatusOK, r, "")
+}
+
+// zoneTotalName returns the zone name used for the totals; the
+// global zone is the empty string like the total UserCC.
+func zoneTotalName(name string) string {
+	if name == "@" {
+		return ""
+	}
+	return name
+}
+
+// setAnswerPoints sets the share of the DNS answers (Points) and the
+// share of the zone netspeed (Netspeed) on the server's answer counts,
+// from the answer totals and zone netspeeds for the same period.
+func setAnswerPoints(serverData chdb.ServerQueries, totalData chdb.ServerTotals, zoneTotals map[string]int32, serverNetspeed uint32) {
+	for _, cc := range serverData {
+		if total := totalData[cc.CC]; total > 0 {
+			cc.Points = (pointBasis / float64(total)) * float64(cc.Count)
+		}
+		totalName := cc.CC
+		if totalName == "gb" {
+			totalName = "uk"
+		}
+		if zt, ok := zoneTotals[totalName]; ok {
+			// log.InfoContext(ctx, "netspeed data", "pointBasis", pointBasis, "zt", zt, "server netspeed", serverNetspeed)
+			cc.Netspeed = netspeedPoints(zt, serverNetspeed)
+		}
+		// log.DebugContext(ctx, "points", "cc", cc.CC, "points", cc.Points)
+	}
+}
 
+// netspeedPoints returns the server's share in points of the zone
+// netspeed zt.
+func netspeedPoints(zt int32, serverNetspeed uint32) float64 {
+	if zt == 0 {
+		// if the recorded netspeed for the zone was zero, assume it's at least
+		// this servers worth instead. Otherwise the Netspeed gets to be 'infinite'.
+		zt = int32(serverNetspeed)
+	}
+	if zt <= 0 {
+		return 0
+	}
+	return (pointBasis / float64(zt)) * float64(serverNetspeed)
 }
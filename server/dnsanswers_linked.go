@@ -0,0 +1,243 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+
+	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/otel/attribute"
+	"golang.org/x/sync/errgroup"
+
+	"go.ntppool.org/common/logger"
+	"go.ntppool.org/common/tracing"
+	chdb "go.ntppool.org/data-api/chdb"
+	"go.ntppool.org/data-api/ntpdb"
+)
+
+// linkedZoneStatsConcurrency is how many of the zone netspeed
+// queries for the servers are done at a time
+const linkedZoneStatsConcurrency = 4
+
+// linkedServerAnswers is the DNS answers for one of the server IPs
+type linkedServerAnswers struct {
+	IP     string
+	Qtype  string
+	Server chdb.ServerQueries
+}
+
+// combinedAnswers is the DNS answers for all the server IPs in a
+// country; Points and Netspeed are the shares of the answers and
+// zone netspeed across the query types of the servers.
+type combinedAnswers struct {
+	CC       string
+	Count    uint64
+	Points   float64
+	Netspeed float64
+}
+
+// dnsAnswersHostname returns the DNS answers for each server with
+// the hostname and for all of them combined.
+func (srv *Server) dnsAnswersHostname(c echo.Context) error {
+	hostname := c.Param("hostname")
+	return srv.dnsAnswersLinked(c, "hostname", hostname, func(ctx context.Context) ([]ntpdb.Server, error) {
+		return srv.db.GetServersByHostname(ctx, sql.NullString{String: hostname, Valid: true})
+	})
+}
+
+// dnsAnswersAccount returns the DNS answers for each server in the
+// account (by id token or url slug; only for accounts with a public
+// profile) and for all of them combined.
+func (srv *Server) dnsAnswersAccount(c echo.Context) error {
+	account := c.Param("account")
+	return srv.dnsAnswersLinked(c, "account", account, func(ctx context.Context) ([]ntpdb.Server, error) {
+		return srv.db.GetServersByAccount(ctx, account)
+	})
+}
+
+func (srv *Server) dnsAnswersLinked(c echo.Context, kind, param string, getServers func(ctx context.Context) ([]ntpdb.Server, error)) error {
+	log := logger.Setup()
+	ctx, span := tracing.Tracer().Start(c.Request().Context(), "dnsanswers.linked")
+	defer span.End()
+
+	// for errors and 404s, a shorter cache time
+	c.Response().Header().Set("Cache-Control", "public,max-age=300")
+
+	log = log.With(kind, param)
+	span.SetAttributes(attribute.String(kind, param))
+
+	if len(param) == 0 {
+		return c.NoContent(http.StatusBadRequest)
+	}
+
+	if len(c.QueryString()) > 0 {
+		// better URLs are forever
+		c.Response().Header().Set("Cache-Control", "public,max-age=10400")
+		return c.Redirect(http.StatusPermanentRedirect,
+			"https://www.ntppool.org/api/data/dns/answers/"+kind+"/"+url.PathEscape(param))
+	}
+
+	servers, err := getServers(ctx)
+	if err != nil {
+		log.Error("could not get servers", "err", err)
+		return c.String(http.StatusInternalServerError, "database error")
+	}
+	if len(servers) == 0 {
+		return c.String(http.StatusNotFound, "Not found")
+	}
+
+	answers, combined, err := srv.linkedAnswers(ctx, servers, 3)
+	if err != nil {
+		log.Error("query error", "err", err)
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+
+	r := struct {
+		Servers     []linkedServerAnswers
+		Combined    []*combinedAnswers
+		PointSymbol string
+	}{
+		Servers:     answers,
+		Combined:    combined,
+		PointSymbol: pointSymbol,
+	}
+
+	c.Response().Header().Set("Cache-Control", "public,max-age=1800")
+
+	return c.JSONPretty(http.StatusOK, r, "")
+}
+
+// linkedAnswers returns the DNS answers in the last days for each of
+// the servers, with the points and netspeed share set from the totals
+// for the server's query type, and the answers for all the servers
+// combined by country. The ClickHouse queries are done once for all
+// the servers and the zone netspeed queries concurrently.
+func (srv *Server) linkedAnswers(ctx context.Context, servers []ntpdb.Server, days int) ([]linkedServerAnswers, []*combinedAnswers, error) {
+	log := logger.FromContext(ctx)
+
+	ips := []string{}
+	qtypes := []string{}
+	for _, s := range servers {
+		ips = append(ips, s.Ip)
+		if qt := serverQtype(s); !slices.Contains(qtypes, qt) {
+			qtypes = append(qtypes, qt)
+		}
+	}
+
+	queryGroup, ctx := errgroup.WithContext(ctx)
+
+	// zone netspeed by zone name, for each server
+	zoneTotals := make([]map[string]int32, len(servers))
+
+	queryGroup.Go(func() error {
+		zoneGroup, ctx := errgroup.WithContext(ctx)
+		zoneGroup.SetLimit(linkedZoneStatsConcurrency)
+		for i, s := range servers {
+			zoneGroup.Go(func() error {
+				zoneStats, err := srv.db.GetZoneStatsV2(ctx, s.Ip)
+				if err != nil {
+					log.Error("GetZoneStatsV2", "server", s.Ip, "err", err)
+					return err
+				}
+				totals := map[string]int32{}
+				for _, z := range zoneStats {
+					totals[zoneTotalName(z.ZoneName)] = z.NetspeedActive
+				}
+				zoneTotals[i] = totals
+				return nil
+			})
+		}
+		return zoneGroup.Wait()
+	})
+
+	var serverData map[string]chdb.ServerQueries
+
+	queryGroup.Go(func() error {
+		var err error
+		serverData, err = srv.ch.ServersAnswerCounts(ctx, ips, days)
+		if err != nil {
+			log.Error("ServersAnswerCounts", "err", err)
+		}
+		return err
+	})
+
+	var totalData map[string]chdb.ServerTotals
+
+	queryGroup.Go(func() error {
+		var err error
+		totalData, err = srv.ch.AnswerTotalsByQtype(ctx, qtypes, days)
+		if err != nil {
+			log.Error("AnswerTotalsByQtype", "err", err)
+		}
+		return err
+	})
+
+	err := queryGroup.Wait()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rv := []linkedServerAnswers{}
+
+	combined := map[string]*combinedAnswers{}
+	// expected answers from the netspeed share, by country
+	expected := map[string]float64{}
+
+	for i, s := range servers {
+		qtype := serverQtype(s)
+		data := serverData[s.Ip]
+		if data == nil {
+			data = chdb.ServerQueries{}
+		}
+
+		setAnswerPoints(data, totalData[qtype], zoneTotals[i], s.Netspeed)
+
+		for _, cc := range data {
+			cb, ok := combined[cc.CC]
+			if !ok {
+				cb = &combinedAnswers{CC: cc.CC}
+				combined[cc.CC] = cb
+			}
+			cb.Count += cc.Count
+			expected[cc.CC] += cc.Netspeed / pointBasis * float64(totalData[qtype][cc.CC])
+		}
+
+		rv = append(rv, linkedServerAnswers{
+			IP:     s.Ip,
+			Qtype:  qtype,
+			Server: data,
+		})
+	}
+
+	combinedData := []*combinedAnswers{}
+	for cc, cb := range combined {
+		var total uint64
+		for _, qtype := range qtypes {
+			total += totalData[qtype][cc]
+		}
+		if total > 0 {
+			cb.Points = (pointBasis / float64(total)) * float64(cb.Count)
+			cb.Netspeed = (pointBasis / float64(total)) * expected[cc]
+		}
+		combinedData = append(combinedData, cb)
+	}
+	sort.Slice(combinedData, func(i, j int) bool {
+		if combinedData[i].Count != combinedData[j].Count {
+			return combinedData[i].Count > combinedData[j].Count
+		}
+		return combinedData[i].CC < combinedData[j].CC
+	})
+
+	return rv, combinedData, nil
+}
+
+// serverQtype returns the DNS query type the server is answered for
+func serverQtype(s ntpdb.Server) string {
+	if s.IpVersion == ntpdb.ServersIpVersionV6 {
+		return "AAAA"
+	}
+	return "A"
+}
@@ -0,0 +1,88 @@
+package server
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/otel/attribute"
+
+	"go.ntppool.org/common/logger"
+	"go.ntppool.org/common/tracing"
+	chdb "go.ntppool.org/data-api/chdb"
+	"go.ntppool.org/data-api/ntpdb"
+)
+
+const (
+	dnsCountsDefaultRange = 2 * time.Hour
+	dnsCountsDefaultStep  = 5 * time.Minute
+
+	// the counts are read from the by_origin_1s table, so the range
+	// is limited to what can be summed within the request timeout
+	dnsCountsMaxRange = 31 * 24 * time.Hour
+)
+
+// dnsCountsDefaultOrigins are the origins counted if the origin
+// parameter isn't set
+var dnsCountsDefaultOrigins = []string{"pool.ntp.org", "g.ntpns.org"}
+
+// dnsQueryCounts returns the average and max DNS queries per second
+// in buckets of 'step' from 'from' to 'to' (unix timestamps) for the
+// origin(s). The defaults are the last two hours in five minute
+// buckets for the pool.ntp.org and g.ntpns.org origins.
+func (srv *Server) dnsQueryCounts(c echo.Context) error {
+	log := logger.Setup()
+	ctx, span := tracing.Tracer().Start(c.Request().Context(), "dnsQueryCounts")
+	defer span.End()
+
+	now := time.Now()
+
+	from, to, step, err := parseStepRange(c, now, dnsCountsDefaultRange, dnsCountsMaxRange, dnsCountsDefaultStep)
+	if err != nil {
+		return err
+	}
+	r := chdb.DNSQueryRange{From: from, To: to, Step: step}
+
+	if origins := c.QueryParams()["origin"]; len(origins) > 0 {
+		roots, err := srv.db.GetDNSRoots(ctx)
+		if err != nil {
+			log.ErrorContext(ctx, "GetDNSRoots", "err", err)
+			return c.String(http.StatusInternalServerError, "database error")
+		}
+
+		for _, param := range origins {
+			for _, origin := range strings.Split(param, ",") {
+				origin = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(origin)), ".")
+				if !slices.ContainsFunc(roots, func(root ntpdb.DnsRoot) bool { return root.Origin == origin }) {
+					return echo.NewHTTPError(http.StatusBadRequest, "unknown origin "+strconv.Quote(origin))
+				}
+				if !slices.Contains(r.Origins, origin) {
+					r.Origins = append(r.Origins, origin)
+				}
+			}
+		}
+	} else {
+		r.Origins = dnsCountsDefaultOrigins
+	}
+
+	span.SetAttributes(
+		attribute.Int64("from", r.From.Unix()),
+		attribute.Int64("to", r.To.Unix()),
+		attribute.String("step", r.Step.String()),
+		attribute.StringSlice("origins", r.Origins),
+	)
+
+	data, err := srv.ch.DNSQueries(ctx, r)
+	if err != nil {
+		log.ErrorContext(ctx, "dnsQueryCounts", "err", err)
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+
+	hdr := c.Response().Header()
+	hdr.Set("Cache-Control", stepCacheControl(r.To, r.Step, now))
+
+	return c.JSON(http.StatusOK, data)
+}
@@ -0,0 +1,245 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"go.ntppool.org/common/logger"
+	"go.ntppool.org/common/tracing"
+	chdb "go.ntppool.org/data-api/chdb"
+)
+
+// freshnessInterval is how often the data freshness is checked
+const freshnessInterval = time.Minute
+
+// dataStatus is how recent the data in the databases is and how far
+// the scorers have gotten
+type dataStatus struct {
+	Updated time.Time
+	Sources []*sourceStatus
+	Scorers []scorerStatus
+}
+
+// sourceStatus is the newest data from a database table; the Age
+// (in seconds) is as of when the status was requested.
+type sourceStatus struct {
+	Source string
+	Newest *time.Time `json:",omitempty"`
+	Age    *float64   `json:",omitempty"`
+	Error  string     `json:",omitempty"`
+}
+
+// scorerStatus is the progress of a scorer through the log scores;
+// LogScoreTime is the time of the last log score it processed.
+type scorerStatus struct {
+	ID           uint32
+	Name         string
+	Status       string
+	LogScoreID   *uint64    `json:",omitempty"`
+	LogScoreTime *time.Time `json:",omitempty"`
+	Age          *float64   `json:",omitempty"`
+	Modified     *time.Time `json:",omitempty"`
+}
+
+// freshnessMetrics are the gauges for the data freshness
+type freshnessMetrics struct {
+	newest      *prometheus.GaugeVec
+	age         *prometheus.GaugeVec
+	scorerID    *prometheus.GaugeVec
+	scorerAge   *prometheus.GaugeVec
+	lastChecked prometheus.Gauge
+}
+
+func newFreshnessMetrics(reg prometheus.Registerer) *freshnessMetrics {
+	m := &freshnessMetrics{
+		newest: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "dataapi_data_newest_timestamp_seconds",
+			Help: "Time of the newest data in the source table",
+		}, []string{"source"}),
+		age: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "dataapi_data_age_seconds",
+			Help: "Age of the newest data in the source table when it was checked",
+		}, []string{"source"}),
+		scorerID: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "dataapi_scorer_log_score_id",
+			Help: "Last log score id processed by the scorer",
+		}, []string{"scorer"}),
+		scorerAge: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "dataapi_scorer_age_seconds",
+			Help: "Age of the last log score processed by the scorer when it was checked",
+		}, []string{"scorer"}),
+		lastChecked: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "dataapi_data_checked_timestamp_seconds",
+			Help: "Time the data freshness was last checked",
+		}),
+	}
+
+	reg.MustRegister(m.newest, m.age, m.scorerID, m.scorerAge, m.lastChecked)
+
+	return m
+}
+
+func (m *freshnessMetrics) update(st *dataStatus) {
+	for _, src := range st.Sources {
+		if src.Newest == nil {
+			continue
+		}
+		m.newest.WithLabelValues(src.Source).Set(float64(src.Newest.Unix()))
+		m.age.WithLabelValues(src.Source).Set(st.Updated.Sub(*src.Newest).Seconds())
+	}
+	for _, sc := range st.Scorers {
+		if sc.LogScoreID != nil {
+			m.scorerID.WithLabelValues(sc.Name).Set(float64(*sc.LogScoreID))
+		}
+		if sc.LogScoreTime != nil {
+			m.scorerAge.WithLabelValues(sc.Name).Set(st.Updated.Sub(*sc.LogScoreTime).Seconds())
+		}
+	}
+	m.lastChecked.Set(float64(st.Updated.Unix()))
+}
+
+// freshness keeps the last data status
+type freshness struct {
+	status  atomic.Pointer[dataStatus]
+	metrics *freshnessMetrics
+}
+
+// runFreshness checks the data freshness every interval until the
+// context is done.
+func (srv *Server) runFreshness(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		srv.updateFreshness(ctx)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (srv *Server) updateFreshness(ctx context.Context) *dataStatus {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	st := srv.checkFreshness(ctx)
+	srv.freshness.status.Store(st)
+	srv.freshness.metrics.update(st)
+	return st
+}
+
+// checkFreshness gets the newest data in the ClickHouse and MySQL
+// tables and the scorer status; errors are noted in the status.
+func (srv *Server) checkFreshness(ctx context.Context) *dataStatus {
+	log := logger.FromContext(ctx)
+	ctx, span := tracing.Tracer().Start(ctx, "checkFreshness")
+	defer span.End()
+
+	st := &dataStatus{Scorers: []scorerStatus{}}
+
+	source := func(name string, newest time.Time, err error) {
+		src := &sourceStatus{Source: name}
+		if err != nil {
+			log.WarnContext(ctx, "data freshness", "source", name, "err", err)
+			src.Error = err.Error()
+		} else if !newest.IsZero() {
+			src.Newest = &newest
+		}
+		st.Sources = append(st.Sources, src)
+	}
+
+	for _, table := range chdb.DataTables {
+		newest, err := srv.ch.NewestTime(ctx, table)
+		source("clickhouse_"+string(table), newest, err)
+	}
+
+	newest, err := srv.db.GetLatestLogScoreTs(ctx)
+	source("mysql_log_scores", newest, err)
+
+	newest, err = srv.db.GetLatestZoneCountsDate(ctx)
+	source("mysql_zone_server_counts", newest, err)
+
+	scorers, err := srv.db.GetScorers(ctx)
+	if err != nil {
+		log.WarnContext(ctx, "scorer status", "err", err)
+	}
+	for _, s := range scorers {
+		sc := scorerStatus{
+			ID:     s.ID,
+			Name:   s.Hostname,
+			Status: string(s.Status),
+		}
+		if len(sc.Name) == 0 {
+			sc.Name = strconv.Itoa(int(s.ID))
+		}
+		if s.LogScoreID.Valid {
+			id := uint64(s.LogScoreID.Int64)
+			sc.LogScoreID = &id
+		}
+		if s.ModifiedOn.Valid {
+			modified := s.ModifiedOn.Time
+			sc.Modified = &modified
+		}
+		if s.LogScoreTs.Valid {
+			ts := s.LogScoreTs.Time
+			sc.LogScoreTime = &ts
+		}
+		st.Scorers = append(st.Scorers, sc)
+	}
+	sort.Slice(st.Scorers, func(i, j int) bool { return st.Scorers[i].Name < st.Scorers[j].Name })
+
+	st.Updated = time.Now()
+
+	return st
+}
+
+// dataStatus returns how recent the data in the databases is (as
+// of the last check, at most a minute ago) and the scorer progress.
+func (srv *Server) dataStatus(c echo.Context) error {
+	ctx, span := tracing.Tracer().Start(c.Request().Context(), "dataStatus")
+	defer span.End()
+
+	st := srv.freshness.status.Load()
+	if st == nil {
+		// the background check hasn't run yet
+		st = srv.updateFreshness(ctx)
+	}
+
+	now := time.Now()
+	age := func(t *time.Time) *float64 {
+		if t == nil {
+			return nil
+		}
+		a := now.Sub(*t).Seconds()
+		return &a
+	}
+
+	r := dataStatus{
+		Updated: st.Updated,
+		Sources: make([]*sourceStatus, len(st.Sources)),
+		Scorers: make([]scorerStatus, len(st.Scorers)),
+	}
+	for i, src := range st.Sources {
+		s := *src
+		s.Age = age(s.Newest)
+		r.Sources[i] = &s
+	}
+	for i, sc := range st.Scorers {
+		sc.Age = age(sc.LogScoreTime)
+		r.Scorers[i] = sc
+	}
+
+	c.Response().Header().Set("Cache-Control", "public,max-age=30")
+
+	return c.JSONPretty(http.StatusOK, r, "")
+}
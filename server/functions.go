@@ -4,10 +4,13 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
+	"net/http"
 	"net/netip"
 	"strconv"
 	"time"
 
+	"github.com/labstack/echo/v4"
 	"go.ntppool.org/common/logger"
 	"go.ntppool.org/common/tracing"
 	"go.ntppool.org/data-api/ntpdb"
@@ -17,7 +20,7 @@ func (srv *Server) FindServer(ctx context.Context, serverID string) (ntpdb.Serve
 	log := logger.Setup()
 	ctx, span := tracing.Tracer().Start(ctx, "FindServer")
 	defer span.End()
-	q := ntpdb.NewWrappedQuerier(ntpdb.New(srv.db))
+	q := srv.db
 
 	var serverData ntpdb.Server
 	var dberr error
@@ -44,3 +47,157 @@ func (srv *Server) FindServer(ctx context.Context, serverID string) (ntpdb.Serve
 
 	return serverData, nil
 }
+
+// parseDateRange returns the days from the 'from' and 'to' query
+// parameters (YYYY-MM-DD or unix timestamps), truncated to the day
+// in UTC. 'to' defaults to today and 'from' to defaultDays before
+// 'to'; ranges over maxDays are rejected.
+func parseDateRange(c echo.Context, defaultDays, maxDays int) (time.Time, time.Time, error) {
+	parse := func(name string) (time.Time, error) {
+		s := c.QueryParam(name)
+		if len(s) == 0 {
+			return time.Time{}, nil
+		}
+		if t, err := time.Parse(time.DateOnly, s); err == nil {
+			return t, nil
+		}
+		sec, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return time.Time{}, echo.NewHTTPError(http.StatusBadRequest, "invalid "+name+" parameter")
+		}
+		return time.Unix(sec, 0).UTC().Truncate(24 * time.Hour), nil
+	}
+
+	from, err := parse("from")
+	if err != nil {
+		return from, from, err
+	}
+	to, err := parse("to")
+	if err != nil {
+		return from, to, err
+	}
+
+	if to.IsZero() {
+		to = time.Now().UTC().Truncate(24 * time.Hour)
+	}
+	if from.IsZero() {
+		from = to.AddDate(0, 0, -defaultDays)
+	}
+
+	if from.After(to) {
+		return from, to, echo.NewHTTPError(http.StatusBadRequest, "from must be before to")
+	}
+	if to.Sub(from) > time.Duration(maxDays)*24*time.Hour {
+		return from, to, echo.NewHTTPError(http.StatusBadRequest,
+			fmt.Sprintf("time range cannot exceed %d days", maxDays))
+	}
+
+	return from, to, nil
+}
+
+// the step is increased until the response has at most this many
+// points
+const stepRangeMaxPoints = 1000
+
+// stepRangeSteps are the bucket sizes for time series with a step
+// parameter; other steps are rounded up to the next one and steps
+// over the largest are rejected.
+var stepRangeSteps = []time.Duration{
+	time.Minute,
+	5 * time.Minute,
+	15 * time.Minute,
+	time.Hour,
+	6 * time.Hour,
+	24 * time.Hour,
+}
+
+// parseStepRange returns the time range from the 'from' and 'to'
+// (unix timestamps) query parameters and the bucket size from the
+// 'step' parameter (a duration like "5m", or seconds). Ranges over
+// maxRange are rejected. The step is rounded up to one of
+// stepRangeSteps and increased so the range has at most
+// stepRangeMaxPoints buckets.
+func parseStepRange(c echo.Context, now time.Time, defaultRange, maxRange, defaultStep time.Duration) (time.Time, time.Time, time.Duration, error) {
+	from, to, step := now.Add(-defaultRange), now, defaultStep
+
+	parseTime := func(name string) (time.Time, error) {
+		sec, err := strconv.ParseInt(c.QueryParam(name), 10, 64)
+		if err != nil {
+			return time.Time{}, echo.NewHTTPError(http.StatusBadRequest, "invalid "+name+" timestamp")
+		}
+		return time.Unix(sec, 0), nil
+	}
+
+	var err error
+
+	if len(c.QueryParam("to")) > 0 {
+		to, err = parseTime("to")
+		if err != nil {
+			return from, to, step, err
+		}
+		if to.After(now) {
+			to = now
+		}
+		from = to.Add(-defaultRange)
+	}
+
+	if len(c.QueryParam("from")) > 0 {
+		from, err = parseTime("from")
+		if err != nil {
+			return from, to, step, err
+		}
+	}
+
+	if !from.Before(to) {
+		return from, to, step, echo.NewHTTPError(http.StatusBadRequest, "from must be before to")
+	}
+	if to.Sub(from) > maxRange {
+		return from, to, step, echo.NewHTTPError(http.StatusBadRequest,
+			fmt.Sprintf("time range cannot exceed %d days", int(maxRange.Hours()/24)))
+	}
+
+	if s := c.QueryParam("step"); len(s) > 0 {
+		step, err = time.ParseDuration(s)
+		if err != nil {
+			sec, serr := strconv.Atoi(s)
+			if serr != nil {
+				return from, to, step, echo.NewHTTPError(http.StatusBadRequest, "invalid step parameter")
+			}
+			step = time.Duration(sec) * time.Second
+		}
+		if step <= 0 {
+			return from, to, step, echo.NewHTTPError(http.StatusBadRequest, "invalid step parameter")
+		}
+		if maxStep := stepRangeSteps[len(stepRangeSteps)-1]; step > maxStep {
+			return from, to, step, echo.NewHTTPError(http.StatusBadRequest,
+				fmt.Sprintf("step cannot exceed %s", maxStep))
+		}
+	}
+
+	rangeDuration := to.Sub(from)
+	for i, s := range stepRangeSteps {
+		last := i == len(stepRangeSteps)-1
+		if !last && (s < step || rangeDuration/s > stepRangeMaxPoints) {
+			continue
+		}
+		step = s
+		break
+	}
+
+	return from, to, step, nil
+}
+
+// stepCacheControl returns the Cache-Control header for a time series
+// ending at 'to'; ranges ending in the past don't change, otherwise
+// the cache time is a fraction of the step.
+func stepCacheControl(to time.Time, step time.Duration, now time.Time) string {
+	if to.Before(now.Add(-step)) {
+		return "public,max-age=86400"
+	}
+
+	maxAge := int(step.Seconds() / 5)
+	maxAge = max(maxAge, 10)
+	maxAge = min(maxAge, 3600)
+
+	return fmt.Sprintf("s-maxage=%d,max-age=%d", maxAge/2, maxAge)
+}
@@ -2,7 +2,9 @@ package server
 
 import (
 	"context"
+	"errors"
 	"net/http"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -181,7 +183,15 @@ func transformToGrafanaTableFormat(history *logscores.LogScoreHistory, monitors
 		"monitor_data_entries", len(monitorData),
 	)
 
-	for monitorID, logScores := range monitorData {
+	// in monitor ID order so the response is stable
+	monitorIDs := make([]int, 0, len(monitorData))
+	for monitorID := range monitorData {
+		monitorIDs = append(monitorIDs, monitorID)
+	}
+	sort.Ints(monitorIDs)
+
+	for _, monitorID := range monitorIDs {
+		logScores := monitorData[monitorID]
 		if len(logScores) == 0 {
 			logger.Setup().Info("skipping monitor with no data", "monitor_id", monitorID)
 			continue
@@ -321,8 +331,7 @@ func (srv *Server) scoresTimeRange(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusInternalServerError, "internal error")
 	}
 
-	// Query ClickHouse for time range data
-	log.InfoContext(ctx, "executing clickhouse time range query",
+	log.InfoContext(ctx, "executing time range query",
 		"server_id", server.ID,
 		"server_ip", server.Ip,
 		"monitor_id", params.monitorID,
@@ -332,20 +341,34 @@ func (srv *Server) scoresTimeRange(c echo.Context) error {
 		"time_range_duration", params.to.Sub(params.from).String(),
 	)
 
-	logScores, err := srv.ch.LogscoresTimeRange(ctx, int(server.ID), params.monitorID, params.from, params.to, params.maxDataPoints)
+	history, err := srv.historyStore(c).History(ctx, logscores.Query{
+		ServerID:  server.ID,
+		MonitorID: uint32(params.monitorID),
+		From:      params.from,
+		To:        params.to,
+		Limit:     params.maxDataPoints,
+		// Always order by timestamp ASC for Grafana convention
+		Order: logscores.OrderAscending,
+	})
 	if err != nil {
-		log.ErrorContext(ctx, "clickhouse time range query", "err", err,
+		log.ErrorContext(ctx, "time range query", "err", err,
 			"server_id", server.ID,
 			"monitor_id", params.monitorID,
 			"from", params.from,
 			"to", params.to,
 		)
+		var httpError *echo.HTTPError
+		if errors.As(err, &httpError) && httpError.Code < 500 {
+			return httpError
+		}
 		span.RecordError(err)
 		return echo.NewHTTPError(http.StatusInternalServerError, "internal error")
 	}
+	logScores := history.LogScores
 
-	log.InfoContext(ctx, "clickhouse query results",
+	log.InfoContext(ctx, "time range query results",
 		"server_id", server.ID,
+		"source", history.Source,
 		"rows_returned", len(logScores),
 		"first_few_ids", func() []uint64 {
 			ids := make([]uint64, 0, 3)
@@ -359,22 +382,10 @@ func (srv *Server) scoresTimeRange(c echo.Context) error {
 		}(),
 	)
 
-	// Build LogScoreHistory structure for compatibility with existing functions
-	history := &logscores.LogScoreHistory{
-		LogScores: logScores,
-		Monitors:  make(map[int]string),
-	}
-
-	// Get monitor names for the returned data
+	// Get monitor IDs for the returned data
 	monitorIDs := []uint32{}
-	for _, ls := range logScores {
-		if ls.MonitorID.Valid {
-			monitorID := uint32(ls.MonitorID.Int32)
-			if _, exists := history.Monitors[int(monitorID)]; !exists {
-				history.Monitors[int(monitorID)] = ""
-				monitorIDs = append(monitorIDs, monitorID)
-			}
-		}
+	for monitorID := range history.Monitors {
+		monitorIDs = append(monitorIDs, uint32(monitorID))
 	}
 
 	log.InfoContext(ctx, "monitor processing",
@@ -385,7 +396,7 @@ func (srv *Server) scoresTimeRange(c echo.Context) error {
 	// Get monitor details from database for status and display names
 	var monitors []ntpdb.Monitor
 	if len(monitorIDs) > 0 {
-		q := ntpdb.NewWrappedQuerier(ntpdb.New(srv.db))
+		q := srv.db
 		logScoreMonitors, err := q.GetServerScores(ctx, ntpdb.GetServerScoresParams{
 			MonitorIDs: monitorIDs,
 			ServerID:   server.ID,
@@ -450,6 +461,7 @@ func (srv *Server) scoresTimeRange(c echo.Context) error {
 	// Set CORS headers
 	c.Response().Header().Set("Access-Control-Allow-Origin", "*")
 	c.Response().Header().Set("Content-Type", "application/json")
+	c.Response().Header().Set("X-Data-Source", history.Source)
 
 	log.InfoContext(ctx, "time range response final",
 		"server_id", server.ID,
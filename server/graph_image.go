@@ -94,10 +94,7 @@ func (srv *Server) fetchGraph(ctx context.Context, serverIP string) (string, []b
 	// q.Set("graph_only", "1")
 	// pagePath := srv.config.WebURL("/scores/" + serverIP, q)
 
-	serviceHost := os.Getenv("screensnap_service")
-	if len(serviceHost) == 0 {
-		serviceHost = "screensnap"
-	}
+	serviceHost := screensnapHost()
 
 	reqURL := url.URL{
 		Scheme: "http",
@@ -148,3 +145,12 @@ func (srv *Server) fetchGraph(ctx context.Context, serverIP string) (string, []b
 // #         scale_method     => "vector",
 // #     }
 // # );
+
+// screensnapHost is the host (and port) of the screensnap service
+// that renders the graphs
+func screensnapHost() string {
+	if serviceHost := os.Getenv("screensnap_service"); len(serviceHost) > 0 {
+		return serviceHost
+	}
+	return "screensnap"
+}
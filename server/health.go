@@ -0,0 +1,320 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"go.ntppool.org/common/version"
+
+	chdb "go.ntppool.org/data-api/chdb"
+)
+
+// healthReport is the status of the dependencies of the API server
+type healthReport struct {
+	// Status is "ok", "degraded" (an optional dependency is
+	// failing) or "error" (the server isn't ready)
+	Status  string
+	Version string
+	Checks  []*healthCheck `json:",omitempty"`
+}
+
+// healthCheck is the status of one dependency; Latency is in
+// milliseconds. The server is only ready if the Required checks
+// pass.
+type healthCheck struct {
+	Name     string
+	OK       bool
+	Required bool
+	Latency  float64
+	Error    string `json:",omitempty"`
+
+	Pool      *poolStats        `json:",omitempty"`
+	Freshness []dataFreshness   `json:",omitempty"`
+	Hosts     []chdb.HostStatus `json:",omitempty"`
+	Replicas  []replicaHealth   `json:",omitempty"`
+}
+
+// replicaHealth is the state of a MySQL read replica; Lag is in
+// seconds.
+type replicaHealth struct {
+	Host  string
+	OK    bool
+	Lag   float64
+	Error string `json:",omitempty"`
+	Pool  *poolStats
+}
+
+// poolStats is the MySQL connection pool from sql.DBStats;
+// WaitDuration is in milliseconds.
+type poolStats struct {
+	MaxOpenConnections int
+	OpenConnections    int
+	InUse              int
+	Idle               int
+	WaitCount          int64
+	WaitDuration       float64
+}
+
+// dataFreshness is the time of the newest data in a table and how
+// old it is (in seconds)
+type dataFreshness struct {
+	Table  string
+	Newest *time.Time `json:",omitempty"`
+	Age    float64    `json:",omitempty"`
+	Error  string     `json:",omitempty"`
+}
+
+// livenessHandler only checks that the server is responding
+func (srv *Server) livenessHandler(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		r := &healthReport{Status: "ok", Version: version.Version()}
+		writeHealth(w, req, log, http.StatusOK, r)
+	}
+}
+
+// readinessHandler checks the databases and the other services
+func (srv *Server) readinessHandler(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		ctx, cancel := context.WithTimeout(req.Context(), 5*time.Second)
+		defer cancel()
+
+		r := srv.healthReport(ctx)
+
+		status := http.StatusOK
+		for _, check := range r.Checks {
+			if !check.OK {
+				log.WarnContext(ctx, "health check failed", "check", check.Name, "err", check.Error)
+			}
+		}
+		if r.Status == "error" {
+			status = http.StatusServiceUnavailable
+		}
+
+		writeHealth(w, req, log, status, r)
+	}
+}
+
+func writeHealth(w http.ResponseWriter, req *http.Request, log *slog.Logger, status int, r *healthReport) {
+	var err error
+	if req.URL.Query().Get("format") == "json" {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(r)
+	} else {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(status)
+		_, err = w.Write([]byte(r.text()))
+	}
+	if err != nil {
+		log.ErrorContext(req.Context(), "could not write response", "err", err)
+	}
+}
+
+// text is the report in the plain text health check format: "ok"
+// or "db ping err" and a line for each check.
+func (r *healthReport) text() string {
+	b := strings.Builder{}
+	if r.Status == "error" {
+		b.WriteString("db ping err\n")
+	} else {
+		b.WriteString("ok\n")
+	}
+	for _, check := range r.Checks {
+		status := "ok"
+		if !check.OK {
+			status = "err: " + check.Error
+		}
+		fmt.Fprintf(&b, "%s %s (%.1fms)\n", check.Name, status, check.Latency)
+		for _, h := range check.Hosts {
+			hostStatus := "ok"
+			if !h.OK {
+				hostStatus = "err: " + h.Error
+			}
+			fmt.Fprintf(&b, "  %s %s\n", h.Host, hostStatus)
+		}
+		for _, rep := range check.Replicas {
+			repStatus := "ok"
+			if !rep.OK {
+				repStatus = "err: " + rep.Error
+			}
+			fmt.Fprintf(&b, "  %s %s (lag %.0fs)\n", rep.Host, repStatus, rep.Lag)
+		}
+	}
+	return b.String()
+}
+
+// healthReport runs the checks in parallel
+func (srv *Server) healthReport(ctx context.Context) *healthReport {
+	type check struct {
+		name     string
+		required bool
+		fn       func(context.Context, *healthCheck) error
+	}
+	checks := []check{
+		{"mysql", true, srv.checkMySQL},
+		// the score history is read from MySQL when the ClickHouse
+		// scores database is down (see logscores.Failover)
+		{"clickhouse scores", false, func(ctx context.Context, hc *healthCheck) error {
+			return srv.checkClickHouse(ctx, hc, "scores")
+		}},
+		{"clickhouse logs", true, func(ctx context.Context, hc *healthCheck) error {
+			return srv.checkClickHouse(ctx, hc, "logs")
+		}},
+		{"screensnap", false, checkScreensnap},
+	}
+	if len(srv.db.ReplicaStatus()) > 0 {
+		// the queries go to the primary if the replicas fail
+		checks = append(checks, check{"mysql replicas", false, srv.checkMySQLReplicas})
+	}
+
+	r := &healthReport{
+		Status:  "ok",
+		Version: version.Version(),
+		Checks:  make([]*healthCheck, len(checks)),
+	}
+
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		hc := &healthCheck{Name: c.name, Required: c.required}
+		r.Checks[i] = hc
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			start := time.Now()
+			err := c.fn(ctx, hc)
+			hc.Latency = float64(time.Since(start).Microseconds()) / 1000
+			hc.OK = err == nil
+			if err != nil {
+				hc.Error = err.Error()
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, hc := range r.Checks {
+		if hc.OK {
+			continue
+		}
+		if hc.Required {
+			r.Status = "error"
+		} else if r.Status == "ok" {
+			r.Status = "degraded"
+		}
+	}
+
+	return r
+}
+
+// checkMySQL pings the database and gets the pool stats and how old
+// the newest log scores and zone counts are; the data being old
+// doesn't fail the check.
+func (srv *Server) checkMySQL(ctx context.Context, hc *healthCheck) error {
+	hc.Pool = newPoolStats(srv.db.Stats())
+
+	err := srv.db.PingContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, f := range []struct {
+		table  string
+		newest func(context.Context) (time.Time, error)
+	}{
+		{"log_scores", srv.db.GetLatestLogScoreTs},
+		{"zone_server_counts", srv.db.GetLatestZoneCountsDate},
+	} {
+		df := dataFreshness{Table: f.table}
+		newest, err := f.newest(ctx)
+		if err != nil {
+			df.Error = err.Error()
+		} else {
+			df.Newest = &newest
+			df.Age = now.Sub(newest).Seconds()
+		}
+		hc.Freshness = append(hc.Freshness, df)
+	}
+
+	return nil
+}
+
+// checkMySQLReplicas reports the state of the read replicas from
+// the last ping or replication check; it fails if any of them isn't
+// used for queries.
+func (srv *Server) checkMySQLReplicas(ctx context.Context, hc *healthCheck) error {
+	var failed []string
+	for _, r := range srv.db.ReplicaStatus() {
+		hc.Replicas = append(hc.Replicas, replicaHealth{
+			Host:  r.Host,
+			OK:    r.Healthy,
+			Lag:   r.Lag.Seconds(),
+			Error: r.Error,
+			Pool:  newPoolStats(r.Stats),
+		})
+		if !r.Healthy {
+			failed = append(failed, r.Host)
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("replicas not used: %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+func newPoolStats(stats sql.DBStats) *poolStats {
+	return &poolStats{
+		MaxOpenConnections: stats.MaxOpenConnections,
+		OpenConnections:    stats.OpenConnections,
+		InUse:              stats.InUse,
+		Idle:               stats.Idle,
+		WaitCount:          stats.WaitCount,
+		WaitDuration:       float64(stats.WaitDuration.Microseconds()) / 1000,
+	}
+}
+
+// checkClickHouse pings each of the hosts of the database; a host
+// being down doesn't fail the check if the connections can use the
+// others.
+func (srv *Server) checkClickHouse(ctx context.Context, hc *healthCheck, database string) error {
+	hc.Hosts = srv.ch.HostStatus(ctx, database)
+
+	errs := []error{}
+	for _, h := range hc.Hosts {
+		if h.OK {
+			return nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %s", h.Host, h.Error))
+	}
+	if len(errs) == 0 {
+		return fmt.Errorf("no %s hosts configured", database)
+	}
+	return errors.Join(errs...)
+}
+
+// checkScreensnap checks that the graph rendering service responds
+func checkScreensnap(ctx context.Context, hc *healthCheck) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+screensnapHost()+"/", nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 500 {
+		return fmt.Errorf("http status %d", resp.StatusCode)
+	}
+	return nil
+}
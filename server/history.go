@@ -86,7 +86,7 @@ func (srv *Server) getHistoryParameters(ctx context.Context, c echo.Context, ser
 	}
 	p.limit = limit
 
-	q := ntpdb.NewWrappedQuerier(ntpdb.New(srv.db))
+	q := srv.db
 
 	monitorParam := c.QueryParam("monitor")
 
@@ -170,9 +170,16 @@ func (srv *Server) getHistoryParameters(ctx context.Context, c echo.Context, ser
 	return p, nil
 }
 
-func (srv *Server) getHistoryMySQL(ctx context.Context, _ echo.Context, p historyParameters) (*logscores.LogScoreHistory, error) {
-	ls, err := logscores.GetHistoryMySQL(ctx, srv.db, p.server.ID, uint32(p.monitorID), p.since, p.limit)
-	return ls, err
+// historyStore returns the log score store for the request; an
+// explicit source parameter skips the failover.
+func (srv *Server) historyStore(c echo.Context) logscores.Store {
+	if s, ok := srv.historySources[c.QueryParam("source")]; ok {
+		return s
+	}
+	if s, ok := srv.historySources[os.Getenv("default_source")]; ok && s.Name() != "clickhouse" {
+		return s
+	}
+	return srv.historyFailover
 }
 
 func (srv *Server) history(c echo.Context) error {
@@ -219,21 +226,13 @@ func (srv *Server) history(c echo.Context) error {
 
 	p.server = server
 
-	var history *logscores.LogScoreHistory
-
-	sourceParam := c.QueryParam("source")
-	switch sourceParam {
-	case "m":
-	case "c":
-	default:
-		sourceParam = os.Getenv("default_source")
-	}
-
-	if sourceParam == "m" {
-		history, err = srv.getHistoryMySQL(ctx, c, p)
-	} else {
-		history, err = logscores.GetHistoryClickHouse(ctx, srv.ch, srv.db, p.server.ID, uint32(p.monitorID), p.since, p.limit, p.fullHistory)
-	}
+	history, err := srv.historyStore(c).History(ctx, logscores.Query{
+		ServerID:    p.server.ID,
+		MonitorID:   uint32(p.monitorID),
+		Since:       p.since,
+		Limit:       p.limit,
+		FullHistory: p.fullHistory,
+	})
 	if err != nil {
 		var httpError *echo.HTTPError
 		if errors.As(err, &httpError) {
@@ -250,6 +249,7 @@ func (srv *Server) history(c echo.Context) error {
 	}
 
 	c.Response().Header().Set("Access-Control-Allow-Origin", "*")
+	c.Response().Header().Set("X-Data-Source", history.Source)
 
 	switch mode {
 	case historyModeLog:
@@ -302,7 +302,7 @@ func (srv *Server) historyJSON(ctx context.Context, c echo.Context, server ntpdb
 		monitorIDs = append(monitorIDs, uint32(k))
 	}
 
-	q := ntpdb.NewWrappedQuerier(ntpdb.New(srv.db))
+	q := srv.db
 	logScoreMonitors, err := q.GetServerScores(ctx,
 		ntpdb.GetServerScoresParams{
 			MonitorIDs: monitorIDs,
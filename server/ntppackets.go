@@ -0,0 +1,67 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/otel/attribute"
+
+	"go.ntppool.org/common/logger"
+	"go.ntppool.org/common/tracing"
+)
+
+const (
+	ntpPacketsDefaultRange = 24 * time.Hour
+	ntpPacketsMaxRange     = 400 * 24 * time.Hour
+	ntpPacketsDefaultStep  = 5 * time.Minute
+)
+
+// ntpPackets returns the NTP packets to the server in buckets of
+// 'step' from 'from' to 'to' (unix timestamps). It's only available
+// if the NTP packet counts are configured for ClickHouse.
+func (srv *Server) ntpPackets(c echo.Context) error {
+	log := logger.Setup()
+	ctx, span := tracing.Tracer().Start(c.Request().Context(), "ntpPackets")
+	defer span.End()
+
+	// for errors and 404s, a shorter cache time
+	c.Response().Header().Set("Cache-Control", "public,max-age=300")
+
+	if !srv.ch.NTPPacketsEnabled() {
+		return c.String(http.StatusNotFound, "Not found")
+	}
+
+	server, err := srv.FindServer(ctx, c.Param("server"))
+	if err != nil {
+		log.ErrorContext(ctx, "find server", "err", err)
+		return c.String(http.StatusInternalServerError, "internal error")
+	}
+	if server.ID == 0 {
+		return c.String(http.StatusNotFound, "server not found")
+	}
+
+	now := time.Now()
+
+	from, to, step, err := parseStepRange(c, now, ntpPacketsDefaultRange, ntpPacketsMaxRange, ntpPacketsDefaultStep)
+	if err != nil {
+		return err
+	}
+
+	span.SetAttributes(
+		attribute.Int("server", int(server.ID)),
+		attribute.Int64("from", from.Unix()),
+		attribute.Int64("to", to.Unix()),
+		attribute.String("step", step.String()),
+	)
+
+	data, err := srv.ch.NTPPackets(ctx, server.Ip, from, to, step)
+	if err != nil {
+		log.ErrorContext(ctx, "NTPPackets", "err", err)
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+
+	c.Response().Header().Set("Cache-Control", stepCacheControl(to, step, now))
+
+	return c.JSON(http.StatusOK, data)
+}
@@ -0,0 +1,43 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	chdb "go.ntppool.org/data-api/chdb"
+)
+
+const (
+	// queryTimeout is the deadline for the database queries for
+	// a request
+	queryTimeout = 15 * time.Second
+
+	// queryTimeoutLong is the deadline for the routes querying long
+	// time ranges
+	queryTimeoutLong = 60 * time.Second
+)
+
+// queryContext returns middleware setting a deadline on the request
+// context and the client IP as the ClickHouse quota key. Queries are
+// cancelled when the deadline passes or the client disconnects.
+func queryContext(timeout time.Duration) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			ctx, cancel := context.WithTimeout(c.Request().Context(), timeout)
+			defer cancel()
+
+			ctx = chdb.WithQuotaKey(ctx, c.RealIP())
+
+			span := trace.SpanFromContext(ctx)
+			span.SetAttributes(attribute.String("query.timeout", timeout.String()))
+
+			c.SetRequest(c.Request().WithContext(ctx))
+
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package server
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"golang.org/x/sync/errgroup"
+
+	"go.ntppool.org/common/logger"
+	"go.ntppool.org/common/tracing"
+	"go.ntppool.org/data-api/ntpdb"
+)
+
+// scorerPosition is how far a scorer has gotten through the log
+// scores. Lag is the number of log score ids it's behind the newest
+// and SinceUpdate is the seconds since it last updated its status;
+// neither is set if the scorer hasn't started.
+type scorerPosition struct {
+	ID          uint32
+	Name        string
+	Status      string
+	LogScoreID  *uint64    `json:",omitempty"`
+	Lag         *uint64    `json:",omitempty"`
+	Modified    *time.Time `json:",omitempty"`
+	SinceUpdate *float64   `json:",omitempty"`
+}
+
+// scorersStatus is the scorer positions and the newest log score
+type scorersStatus struct {
+	NewestLogScoreID uint64
+	Scorers          []scorerPosition
+}
+
+// scorers lists the scorer monitors and their position in the log
+// scores.
+func (srv *Server) scorers(c echo.Context) error {
+	log := logger.Setup()
+	ctx, span := tracing.Tracer().Start(c.Request().Context(), "scorers")
+	defer span.End()
+
+	if len(c.QueryString()) > 0 {
+		// better URLs are forever
+		c.Response().Header().Set("Cache-Control", "public,max-age=10400")
+		return c.Redirect(http.StatusPermanentRedirect, "https://www.ntppool.org/api/data/scorers")
+	}
+
+	queryGroup, ctx := errgroup.WithContext(ctx)
+
+	var newest uint64
+
+	queryGroup.Go(func() error {
+		var err error
+		newest, err = srv.db.GetLatestLogScoreID(ctx)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil
+			}
+			log.ErrorContext(ctx, "GetLatestLogScoreID", "err", err)
+		}
+		return err
+	})
+
+	var scorers []ntpdb.GetScorersRow
+
+	queryGroup.Go(func() error {
+		var err error
+		scorers, err = srv.db.GetScorers(ctx)
+		if err != nil {
+			log.ErrorContext(ctx, "GetScorers", "err", err)
+		}
+		return err
+	})
+
+	err := queryGroup.Wait()
+	if err != nil {
+		c.Response().Header().Set("Cache-Control", "public,max-age=300")
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+
+	now := time.Now()
+
+	rv := scorersStatus{
+		NewestLogScoreID: newest,
+		Scorers:          make([]scorerPosition, 0, len(scorers)),
+	}
+	for _, s := range scorers {
+		p := scorerPosition{
+			ID:     s.ID,
+			Name:   s.Hostname,
+			Status: string(s.Status),
+		}
+		if s.LogScoreID.Valid {
+			id := uint64(s.LogScoreID.Int64)
+			p.LogScoreID = &id
+			var lag uint64
+			if newest > id {
+				lag = newest - id
+			}
+			p.Lag = &lag
+		}
+		if s.ModifiedOn.Valid {
+			modified := s.ModifiedOn.Time
+			p.Modified = &modified
+			since := now.Sub(modified).Seconds()
+			p.SinceUpdate = &since
+		}
+		rv.Scorers = append(rv.Scorers, p)
+	}
+
+	c.Response().Header().Set("Cache-Control", "public,max-age=60")
+
+	return c.JSONPretty(http.StatusOK, rv, "")
+}
@@ -2,13 +2,11 @@ package server
 
 import (
 	"context"
-	"database/sql"
 	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
-	"strconv"
 	"time"
 
 	"golang.org/x/sync/errgroup"
@@ -32,14 +30,20 @@ import (
 	"go.ntppool.org/api/config"
 
 	chdb "go.ntppool.org/data-api/chdb"
+	"go.ntppool.org/data-api/logscores"
 	"go.ntppool.org/data-api/ntpdb"
 )
 
 type Server struct {
-	db     *sql.DB
-	ch     *chdb.ClickHouse
+	db     ntpdb.DB
+	ch     chdb.Querier
 	config *config.Config
 
+	historySources  map[string]logscores.Store
+	historyFailover *logscores.Failover
+
+	freshness freshness
+
 	ctx context.Context
 
 	metrics    *metricsserver.Metrics
@@ -58,22 +62,19 @@ func NewServer(ctx context.Context, configFile string) (*Server, error) {
 		return nil, fmt.Errorf("mysql open: %w", err)
 	}
 
-	conf := config.New()
-	if !conf.Valid() {
-		log.Error("invalid ntppool config")
+	replicas, err := ntpdb.OpenReplicas(ctx, configFile)
+	if err != nil {
+		return nil, fmt.Errorf("mysql replicas: %w", err)
 	}
 
-	srv := &Server{
-		ch:      ch,
-		db:      db,
-		ctx:     ctx,
-		config:  conf,
-		metrics: metricsserver.New(),
+	srv := New(ctx, ntpdb.NewReplicatedDB(db, replicas), ch)
+	if !srv.config.Valid() {
+		log.Error("invalid ntppool config")
 	}
 
 	tpShutdown, err := tracing.InitTracer(ctx, &tracing.TracerConfig{
 		ServiceName: "data-api",
-		Environment: conf.DeploymentMode(),
+		Environment: srv.config.DeploymentMode(),
 	})
 	if err != nil {
 		return nil, fmt.Errorf("tracing init: %w", err)
@@ -83,11 +84,41 @@ func NewServer(ctx context.Context, configFile string) (*Server, error) {
 	return srv, nil
 }
 
+// New returns a server using the databases; NewServer opens them
+// from the configuration file.
+func New(ctx context.Context, db ntpdb.DB, ch chdb.Querier) *Server {
+	srv := &Server{
+		ch:      ch,
+		db:      db,
+		ctx:     ctx,
+		config:  config.New(),
+		metrics: metricsserver.New(),
+	}
+
+	if err := chdb.RegisterMetrics(srv.metrics.Registry()); err != nil {
+		logger.Setup().Error("could not register clickhouse metrics", "err", err)
+	}
+	if err := ntpdb.RegisterMetrics(srv.metrics.Registry(), db); err != nil {
+		logger.Setup().Error("could not register mysql metrics", "err", err)
+	}
+	srv.freshness.metrics = newFreshnessMetrics(srv.metrics.Registry())
+
+	chHistory := logscores.NewClickHouseStore(ch, db)
+	mysqlHistory := logscores.NewMySQLStore(db)
+
+	srv.historySources = map[string]logscores.Store{
+		"c": chHistory,
+		"m": mysqlHistory,
+		"a": logscores.NewArchiveStore(ch, db),
+	}
+	srv.historyFailover = logscores.NewFailover(chHistory, mysqlHistory)
+
+	return srv
+}
+
 func (srv *Server) Run() error {
 	log := logger.Setup()
 
-	ntpconf := config.New()
-
 	ctx, cancel := context.WithCancel(srv.ctx)
 	defer cancel()
 
@@ -98,9 +129,40 @@ func (srv *Server) Run() error {
 		return srv.metrics.ListenAndServe(ctx, 9020)
 	})
 
+	g.Go(func() error {
+		return srv.historyFailover.Run(ctx, 10*time.Second)
+	})
+
+	g.Go(func() error {
+		return srv.runFreshness(ctx, freshnessInterval)
+	})
+
+	if r, ok := srv.db.(interface {
+		CheckReplicas(context.Context, time.Duration) error
+	}); ok {
+		g.Go(func() error {
+			return r.CheckReplicas(ctx, 10*time.Second)
+		})
+	}
+
+	// the ClickHouse connections are re-opened when the
+	// configuration file changes (the MySQL connector reads
+	// it for each new connection)
+	if w, ok := srv.ch.(interface {
+		Watch(context.Context) error
+	}); ok {
+		g.Go(func() error {
+			return w.Watch(ctx)
+		})
+	}
+
 	g.Go(func() error {
 		hclog := log.WithGroup("health")
-		hc := health.NewServer(healthHandler(srv, hclog))
+		ready := srv.readinessHandler(hclog)
+		hc := health.NewServer(ready,
+			health.WithLivenessHandler(srv.livenessHandler(hclog)),
+			health.WithReadinessHandler(ready),
+		)
 		hc.SetLogger(hclog)
 		return hc.Listen(ctx, 9019)
 	})
@@ -195,6 +257,18 @@ func (srv *Server) Run() error {
 		},
 	}))
 
+	srv.routes(e)
+
+	g.Go(func() error {
+		return e.Start(":8030")
+	})
+
+	return g.Wait()
+}
+
+func (srv *Server) routes(e *echo.Echo) {
+	log := logger.Setup()
+
 	e.GET("/hello", func(c echo.Context) error {
 		ctx := c.Request().Context()
 		ctx, span := tracing.Tracer().Start(ctx, "hello")
@@ -204,14 +278,28 @@ func (srv *Server) Run() error {
 		return c.String(http.StatusOK, "Hello")
 	})
 
-	e.GET("/api/usercc", srv.userCountryData)
-	e.GET("/api/server/dns/answers/:server", srv.dnsAnswers)
-	e.GET("/api/server/scores/:server/:mode", srv.history)
-	e.GET("/api/dns/counts", srv.dnsQueryCounts)
-	e.GET("/api/v2/test/grafana-table", srv.testGrafanaTable)
-	e.GET("/api/v2/server/scores/:server/:mode", srv.scoresTimeRange)
-
-	if len(ntpconf.WebHostname()) > 0 {
+	short := queryContext(queryTimeout)
+	long := queryContext(queryTimeoutLong)
+
+	e.GET("/api/status", srv.dataStatus, short)
+	e.GET("/api/scorers", srv.scorers, short)
+	e.GET("/api/usercc", srv.userCountryData, short)
+	e.GET("/api/usercc/history", srv.userCountryHistory, long)
+	e.GET("/api/usercc/capacity", srv.capacity, short)
+	e.GET("/api/servers", srv.serverSearch, short)
+	e.GET("/api/server/:server", srv.serverInfo, short)
+	e.GET("/api/server/dns/answers/:server", srv.dnsAnswers, short)
+	e.GET("/api/server/dns/analysis/:server", srv.dnsAnalysis, short)
+	e.GET("/api/server/ntp/packets/:server", srv.ntpPackets, long)
+	e.GET("/api/dns/answers/hostname/:hostname", srv.dnsAnswersHostname, short)
+	e.GET("/api/dns/answers/account/:account", srv.dnsAnswersAccount, short)
+	e.GET("/api/server/scores/:server/:mode", srv.history, long)
+	e.GET("/api/dns/counts", srv.dnsQueryCounts, short)
+	e.GET("/api/dns/vendor/:zone", srv.vendorZoneQueries, long)
+	e.GET("/api/v2/test/grafana-table", srv.testGrafanaTable, short)
+	e.GET("/api/v2/server/scores/:server/:mode", srv.scoresTimeRange, long)
+
+	if len(srv.config.WebHostname()) > 0 {
 		e.POST("/api/server/scores/:server/:mode", func(c echo.Context) error {
 			// POST requests used to work, so make them not error out
 			mode := c.Param("mode")
@@ -219,22 +307,25 @@ func (srv *Server) Run() error {
 			query := c.Request().URL.Query()
 			return c.Redirect(
 				http.StatusSeeOther,
-				ntpconf.WebURL(
+				srv.config.WebURL(
 					fmt.Sprintf("/scores/%s/%s", server, mode),
 					&query,
 				),
 			)
 		})
 	}
-	e.GET("/graph/:server/:type", srv.graphImage)
+	e.GET("/graph/:server/:type", srv.graphImage, long)
 
-	e.GET("/api/zone/counts/:zone_name", srv.zoneCounts)
-
-	g.Go(func() error {
-		return e.Start(":8030")
-	})
+	e.GET("/api/zone/counts/:zone_name", srv.zoneCounts, short)
+}
 
-	return g.Wait()
+// Handler returns the API routes without the metrics, tracing and
+// logging middleware, for serving with httptest.
+func (srv *Server) Handler() http.Handler {
+	e := echo.New()
+	e.IPExtractor = echo.ExtractIPDirect()
+	srv.routes(e)
+	return e
 }
 
 func (srv *Server) Shutdown(ctx context.Context) error {
@@ -254,7 +345,7 @@ func (srv *Server) userCountryData(c echo.Context) error {
 	ctx, span := tracing.Tracer().Start(c.Request().Context(), "userCountryData")
 	defer span.End()
 
-	q := ntpdb.NewWrappedQuerier(ntpdb.New(srv.db))
+	q := srv.db
 	zoneStats, err := ntpdb.GetZoneStats(ctx, q)
 	if err != nil {
 		log.ErrorContext(ctx, "GetZoneStats", "err", err)
@@ -278,89 +369,3 @@ func (srv *Server) userCountryData(c echo.Context) error {
 		ZoneStats:   zoneStats,
 	})
 }
-
-func (srv *Server) dnsQueryCounts(c echo.Context) error {
-	log := logger.Setup()
-	ctx, span := tracing.Tracer().Start(c.Request().Context(), "dnsQueryCounts")
-	defer span.End()
-
-	data, err := srv.ch.DNSQueries(ctx)
-	if err != nil {
-		log.ErrorContext(ctx, "dnsQueryCounts", "err", err)
-		return c.String(http.StatusInternalServerError, err.Error())
-	}
-
-	hdr := c.Response().Header()
-	hdr.Set("Cache-Control", "s-maxage=30,max-age=60")
-
-	return c.JSON(http.StatusOK, data)
-}
-
-func healthHandler(srv *Server, log *slog.Logger) func(w http.ResponseWriter, req *http.Request) {
-	return func(w http.ResponseWriter, req *http.Request) {
-		ctx := req.Context()
-		ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
-		defer cancel()
-		g, ctx := errgroup.WithContext(ctx)
-
-		stats := srv.db.Stats()
-		if stats.OpenConnections > 3 {
-			log.InfoContext(ctx, "health requests", "url", req.URL.String(), "stats", stats)
-		}
-
-		if resetParam := req.URL.Query().Get("reset"); resetParam != "" {
-			reset, err := strconv.ParseBool(resetParam)
-			log.InfoContext(ctx, "db reset request", "err", err, "reset", reset)
-
-			if err == nil && reset {
-				// this feature was to debug some specific problem
-				log.InfoContext(ctx, "setting idle db conns to zero")
-				srv.db.SetConnMaxLifetime(30 * time.Second)
-				srv.db.SetMaxIdleConns(0)
-				srv.db.SetMaxIdleConns(4)
-			}
-		}
-
-		g.Go(func() error {
-			err := srv.ch.Scores.Ping(ctx)
-			if err != nil {
-				log.WarnContext(ctx, "ch scores ping", "err", err)
-				return err
-			}
-			return nil
-		})
-
-		g.Go(func() error {
-			err := srv.ch.Logs.Ping(ctx)
-			if err != nil {
-				log.WarnContext(ctx, "ch logs ping", "err", err)
-				return err
-			}
-			return nil
-		})
-
-		g.Go(func() error {
-			err := srv.db.PingContext(ctx)
-			if err != nil {
-				log.WarnContext(ctx, "db ping", "err", err)
-				return err
-			}
-			return nil
-		})
-
-		err := g.Wait()
-		if err != nil {
-			w.WriteHeader(http.StatusServiceUnavailable)
-			_, err = w.Write([]byte("db ping err"))
-			if err != nil {
-				log.ErrorContext(ctx, "could not write response", "err", err)
-			}
-			return
-		}
-		w.WriteHeader(http.StatusOK)
-		_, err = w.Write([]byte("ok"))
-		if err != nil {
-			log.ErrorContext(ctx, "could not write response", "err", err)
-		}
-	}
-}
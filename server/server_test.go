@@ -0,0 +1,163 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.ntppool.org/data-api/fakedb"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+// testHandler returns the API routes with the databases from the
+// fixture file in testdata
+func testHandler(t *testing.T, fixture string) http.Handler {
+	t.Helper()
+
+	f, err := fakedb.Load(filepath.Join("testdata", fixture))
+	if err != nil {
+		t.Fatalf("load fixture: %s", err)
+	}
+
+	srv := New(context.Background(), f.MySQL, f.ClickHouse)
+	return srv.Handler()
+}
+
+// checkGolden compares the response body with testdata/name.golden,
+// or writes the file with the -update flag.
+func checkGolden(t *testing.T, name string, body []byte) {
+	t.Helper()
+
+	path := filepath.Join("testdata", name+".golden")
+
+	if *update {
+		if err := os.WriteFile(path, body, 0o644); err != nil {
+			t.Fatalf("update golden file: %s", err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read golden file (run with -update to create it): %s", err)
+	}
+	if !bytes.Equal(body, want) {
+		t.Errorf("response doesn't match %s\n got: %s\nwant: %s", path, body, want)
+	}
+}
+
+func TestHandlers(t *testing.T) {
+	handler := testHandler(t, "fixture.json")
+
+	tests := []struct {
+		name   string
+		url    string
+		status int
+	}{
+		{"history_json", "/api/server/scores/7/json?monitor=*&since=1704060000", http.StatusOK},
+		{"history_json_default_monitor", "/api/server/scores/192.0.2.10/json?since=1704060000", http.StatusOK},
+		{"history_log", "/api/server/scores/7/log?monitor=*&since=1704060000", http.StatusOK},
+		{"history_not_found", "/api/server/scores/99/json", http.StatusNotFound},
+		{"scores_time_range", "/api/v2/server/scores/7/json?from=1704067200&to=1704070800&monitor=*", http.StatusOK},
+		{"scores_time_range_no_from", "/api/v2/server/scores/7/json?to=1704070800", http.StatusBadRequest},
+		{"dns_counts_range_too_long", "/api/dns/counts?from=1700000000&to=1704067200", http.StatusBadRequest},
+		{"dns_counts_step_too_large", "/api/dns/counts?from=1703980800&to=1704067200&step=48h", http.StatusBadRequest},
+		{"zone_counts", "/api/zone/counts/de", http.StatusOK},
+		{"zone_counts_limit", "/api/zone/counts/de?limit=1", http.StatusOK},
+		{"dns_answers", "/api/server/dns/answers/192.0.2.10", http.StatusOK},
+		{"dns_analysis", "/api/server/dns/analysis/192.0.2.10", http.StatusOK},
+		{"dns_answers_hostname", "/api/dns/answers/hostname/ntp1.example.net", http.StatusOK},
+		{"dns_answers_account", "/api/dns/answers/account/example", http.StatusOK},
+		{"dns_answers_private_account", "/api/dns/answers/account/private", http.StatusNotFound},
+		{"user_country", "/api/usercc", http.StatusOK},
+		{"servers", "/api/servers", http.StatusOK},
+		{"servers_by_netspeed", "/api/servers?sort=netspeed&ip_version=4", http.StatusOK},
+		{"servers_private_account", "/api/servers?account=private", http.StatusOK},
+		{"servers_not_in_list", "/api/servers?in_server_list=false", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Fatalf("status %d, expected %d: %s", rec.Code, tt.status, rec.Body.String())
+			}
+
+			checkGolden(t, tt.name, rec.Body.Bytes())
+		})
+	}
+}
+
+// TestServerSearchPages follows the cursors through the pages of the
+// server search.
+func TestServerSearchPages(t *testing.T) {
+	handler := testHandler(t, "fixture.json")
+
+	for _, tt := range []struct {
+		sort string
+		want []uint32
+	}{
+		{"id", []uint32{7, 8}},
+		{"score", []uint32{7, 8}},
+		{"netspeed", []uint32{7, 8}},
+	} {
+		t.Run(tt.sort, func(t *testing.T) {
+			ids := []uint32{}
+			next := ""
+			for page := 0; page < 5; page++ {
+				u := fmt.Sprintf("/api/servers?sort=%s&limit=1", tt.sort)
+				if len(next) > 0 {
+					u += "&cursor=" + next
+				}
+				rec := httptest.NewRecorder()
+				handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, u, nil))
+				if rec.Code != http.StatusOK {
+					t.Fatalf("%s: status %d: %s", u, rec.Code, rec.Body.String())
+				}
+
+				var list serverList
+				if err := json.Unmarshal(rec.Body.Bytes(), &list); err != nil {
+					t.Fatal(err)
+				}
+				for _, s := range list.Servers {
+					ids = append(ids, s.ID)
+				}
+				if next = list.Next; len(next) == 0 {
+					break
+				}
+			}
+			if fmt.Sprint(ids) != fmt.Sprint(tt.want) {
+				t.Errorf("got servers %v, expected %v", ids, tt.want)
+			}
+		})
+	}
+}
+
+func TestDNSAnswersRedirect(t *testing.T) {
+	handler := testHandler(t, "fixture.json")
+
+	req := httptest.NewRequest(http.MethodGet, "/api/server/dns/answers/192.0.2.10?foo=bar", nil)
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusPermanentRedirect {
+		t.Fatalf("status %d, expected %d", rec.Code, http.StatusPermanentRedirect)
+	}
+	want := "https://www.ntppool.org/api/data/server/dns/answers/192.0.2.10"
+	if loc := rec.Header().Get("Location"); loc != want {
+		t.Errorf("location %q, expected %q", loc, want)
+	}
+}
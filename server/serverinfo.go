@@ -0,0 +1,236 @@
+package server
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/otel/attribute"
+	"golang.org/x/sync/errgroup"
+
+	"go.ntppool.org/common/logger"
+	"go.ntppool.org/common/tracing"
+	"go.ntppool.org/data-api/ntpdb"
+)
+
+// serverSummary is the public columns of a server
+type serverSummary struct {
+	ID         uint32
+	IP         string
+	IPVersion  string
+	Hostname   string `json:",omitempty"`
+	Stratum    *int16 `json:",omitempty"`
+	Netspeed   uint32
+	InPool     bool
+	CreatedOn  time.Time
+	DeletionOn *time.Time `json:",omitempty"`
+	ScoreRaw   float64
+}
+
+func newServerSummary(server ntpdb.Server) serverSummary {
+	s := serverSummary{
+		ID:        server.ID,
+		IP:        server.Ip,
+		IPVersion: string(server.IpVersion),
+		Hostname:  server.Hostname.String,
+		Netspeed:  server.Netspeed,
+		InPool:    server.InPool > 0,
+		CreatedOn: server.CreatedOn,
+		ScoreRaw:  server.ScoreRaw,
+	}
+	if server.Stratum.Valid {
+		s.Stratum = &server.Stratum.Int16
+	}
+	if server.DeletionOn.Valid {
+		s.DeletionOn = &server.DeletionOn.Time
+	}
+	return s
+}
+
+// serverDetails is the public information about a server
+type serverDetails struct {
+	serverSummary
+
+	Zones   []string
+	URLs    []string
+	Account *publicAccount `json:",omitempty"`
+
+	// MonitorReviewPending is set if the monitors for the server
+	// are due to be reviewed
+	MonitorReviewPending bool
+
+	Verification serverVerification
+}
+
+// publicAccount is the account of a server, if it has a public
+// profile
+type publicAccount struct {
+	ID               string `json:",omitempty"`
+	URLSlug          string `json:",omitempty"`
+	Name             string `json:",omitempty"`
+	OrganizationName string `json:",omitempty"`
+	OrganizationURL  string `json:",omitempty"`
+}
+
+// serverVerification is whether the ownership of the server has been
+// verified and the history of the verifications, newest first.
+type serverVerification struct {
+	Verified   bool
+	VerifiedOn *time.Time `json:",omitempty"`
+	History    []verificationEvent
+}
+
+// verificationEvent is when a verification was started and, if it
+// was completed, when it was verified
+type verificationEvent struct {
+	Created    time.Time
+	VerifiedOn *time.Time `json:",omitempty"`
+}
+
+// serverInfo returns the details for the server (by IP or ID).
+func (srv *Server) serverInfo(c echo.Context) error {
+	log := logger.Setup()
+	ctx, span := tracing.Tracer().Start(c.Request().Context(), "serverInfo")
+	defer span.End()
+
+	// for errors and 404s, a shorter cache time
+	c.Response().Header().Set("Cache-Control", "public,max-age=300")
+
+	span.SetAttributes(attribute.String("server_param", c.Param("server")))
+
+	server, err := srv.FindServer(ctx, c.Param("server"))
+	if err != nil {
+		log.ErrorContext(ctx, "find server", "err", err)
+		span.RecordError(err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "internal error")
+	}
+	if server.ID == 0 || server.DeletionAge(30*24*time.Hour) {
+		span.AddEvent("server not found")
+		return echo.NewHTTPError(http.StatusNotFound, "server not found")
+	}
+
+	if len(c.QueryString()) > 0 {
+		// better URLs are forever
+		c.Response().Header().Set("Cache-Control", "public,max-age=10400")
+		return c.Redirect(http.StatusPermanentRedirect, "https://www.ntppool.org/api/data/server/"+c.Param("server"))
+	}
+
+	rv := serverDetails{
+		serverSummary: newServerSummary(server),
+	}
+
+	queryGroup, ctx := errgroup.WithContext(ctx)
+
+	queryGroup.Go(func() error {
+		var err error
+		rv.Zones, err = srv.db.GetServerZones(ctx, server.ID)
+		if err != nil {
+			log.ErrorContext(ctx, "GetServerZones", "err", err)
+		}
+		return err
+	})
+
+	queryGroup.Go(func() error {
+		var err error
+		rv.URLs, err = srv.db.GetServerURLs(ctx, server.ID)
+		if err != nil {
+			log.ErrorContext(ctx, "GetServerURLs", "err", err)
+		}
+		return err
+	})
+
+	if server.AccountID.Valid {
+		queryGroup.Go(func() error {
+			a, err := srv.db.GetPublicAccount(ctx, uint32(server.AccountID.Int32))
+			if err != nil {
+				if errors.Is(err, sql.ErrNoRows) {
+					// the account isn't public
+					return nil
+				}
+				log.ErrorContext(ctx, "GetPublicAccount", "err", err)
+				return err
+			}
+			rv.Account = &publicAccount{
+				ID:               a.IDToken.String,
+				URLSlug:          a.UrlSlug.String,
+				Name:             a.Name.String,
+				OrganizationName: a.OrganizationName.String,
+				OrganizationURL:  a.OrganizationUrl.String,
+			}
+			return nil
+		})
+	}
+
+	queryGroup.Go(func() error {
+		review, err := srv.db.GetServerMonitorReview(ctx, server.ID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil
+			}
+			log.ErrorContext(ctx, "GetServerMonitorReview", "err", err)
+			return err
+		}
+		rv.MonitorReviewPending = review.NextReview.Valid && !review.NextReview.Time.After(time.Now())
+		return nil
+	})
+
+	queryGroup.Go(func() error {
+		v, err := srv.db.GetServerVerification(ctx, server.ID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil
+			}
+			log.ErrorContext(ctx, "GetServerVerification", "err", err)
+			return err
+		}
+		if v.VerifiedOn.Valid {
+			rv.Verification.Verified = true
+			rv.Verification.VerifiedOn = &v.VerifiedOn.Time
+		}
+		return nil
+	})
+
+	queryGroup.Go(func() error {
+		history, err := srv.db.GetServerVerificationHistory(ctx, server.ID)
+		if err != nil {
+			log.ErrorContext(ctx, "GetServerVerificationHistory", "err", err)
+			return err
+		}
+		rv.Verification.History = verificationHistory(history)
+		return nil
+	})
+
+	err = queryGroup.Wait()
+	if err != nil {
+		span.RecordError(err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "internal error")
+	}
+
+	if rv.Zones == nil {
+		rv.Zones = []string{}
+	}
+	if rv.URLs == nil {
+		rv.URLs = []string{}
+	}
+
+	c.Response().Header().Set("Cache-Control", "public,max-age=900")
+
+	return c.JSONPretty(http.StatusOK, rv, "")
+}
+
+// verificationHistory returns the verification events without the
+// token and the IPs they were made from
+func verificationHistory(rows []ntpdb.GetServerVerificationHistoryRow) []verificationEvent {
+	events := make([]verificationEvent, 0, len(rows))
+	for _, r := range rows {
+		e := verificationEvent{Created: r.CreatedOn}
+		if r.VerifiedOn.Valid {
+			verifiedOn := r.VerifiedOn.Time
+			e.VerifiedOn = &verifiedOn
+		}
+		events = append(events, e)
+	}
+	return events
+}
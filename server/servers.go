@@ -0,0 +1,247 @@
+package server
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/otel/attribute"
+
+	"go.ntppool.org/common/logger"
+	"go.ntppool.org/common/tracing"
+	"go.ntppool.org/data-api/ntpdb"
+)
+
+const (
+	serverSearchLimit    = 100
+	serverSearchLimitMax = 500
+)
+
+// serverList is a page of the server search; Next is the cursor
+// for the next page if there are more servers.
+type serverList struct {
+	Servers []serverSummary
+	Next    string `json:",omitempty"`
+}
+
+// serverSearchParams are the filters and the position in the
+// results from the query parameters
+type serverSearchParams struct {
+	sort  string
+	limit int32
+
+	zone        sql.NullString
+	ipVersion   ntpdb.NullServersIpVersion
+	minScore    sql.NullFloat64
+	minNetspeed sql.NullInt32
+	maxNetspeed sql.NullInt32
+	hostname    sql.NullString
+	account     sql.NullString
+
+	// the sort value (0 for the id sort) and ID of the last server
+	// on the previous page; afterValue is nil for the first page
+	afterValue *float64
+	afterID    uint32
+}
+
+// serverSearch lists the servers shown on the public site (in the
+// server list, not scheduled for deletion and in an account with a
+// public profile) matching the filters in the query parameters:
+// zone, ip_version, min_score, min_netspeed, max_netspeed, hostname
+// (a substring) and account (the URL slug of the account).
+// The servers are sorted by ID, or with sort=score or
+// sort=netspeed by the highest first; the cursor parameter is the
+// Next value from the previous page.
+func (srv *Server) serverSearch(c echo.Context) error {
+	log := logger.Setup()
+	ctx, span := tracing.Tracer().Start(c.Request().Context(), "serverSearch")
+	defer span.End()
+
+	c.Response().Header().Set("Cache-Control", "public,max-age=300")
+
+	p, err := parseServerSearch(c)
+	if err != nil {
+		return err
+	}
+
+	span.SetAttributes(attribute.String("sort", p.sort))
+
+	// get one more than the limit to know if there's a next page
+	limit := p.limit + 1
+
+	arg := ntpdb.SearchServersParams{
+		Zone:        p.zone,
+		IpVersion:   p.ipVersion,
+		MinScore:    p.minScore,
+		MinNetspeed: p.minNetspeed,
+		MaxNetspeed: p.maxNetspeed,
+		Hostname:    p.hostname,
+		Account:     p.account,
+		Sort:        p.sort,
+		AfterID:     p.afterID,
+		Limit:       limit,
+	}
+	if p.afterValue != nil {
+		arg.AfterValue = *p.afterValue
+	}
+
+	servers, err := srv.db.SearchServers(ctx, arg)
+	if err != nil {
+		log.ErrorContext(ctx, "search servers", "sort", p.sort, "err", err)
+		span.RecordError(err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "internal error")
+	}
+
+	rv := serverList{Servers: []serverSummary{}}
+
+	if len(servers) > int(p.limit) {
+		servers = servers[:p.limit]
+		last := servers[len(servers)-1]
+		value := "0"
+		switch p.sort {
+		case "score":
+			value = strconv.FormatFloat(last.ScoreRaw, 'g', -1, 64)
+		case "netspeed":
+			value = strconv.FormatUint(uint64(last.Netspeed), 10)
+		}
+		rv.Next = encodeServerCursor(p.sort, value, last.ID)
+	}
+
+	for _, s := range servers {
+		rv.Servers = append(rv.Servers, newServerSummary(s))
+	}
+
+	return c.JSONPretty(http.StatusOK, rv, "")
+}
+
+// parseServerSearch returns the search parameters; invalid values
+// are a bad request error.
+func parseServerSearch(c echo.Context) (*serverSearchParams, error) {
+	p := &serverSearchParams{
+		sort:  "id",
+		limit: serverSearchLimit,
+	}
+
+	invalid := func(name string) error {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid "+name+" parameter")
+	}
+
+	if s := c.QueryParam("sort"); len(s) > 0 {
+		switch s {
+		case "id", "score", "netspeed":
+			p.sort = s
+		default:
+			return nil, invalid("sort")
+		}
+	}
+
+	if s := c.QueryParam("limit"); len(s) > 0 {
+		limit, err := strconv.Atoi(s)
+		if err != nil || limit <= 0 {
+			return nil, invalid("limit")
+		}
+		p.limit = int32(min(limit, serverSearchLimitMax))
+	}
+
+	str := func(name string) sql.NullString {
+		s := c.QueryParam(name)
+		return sql.NullString{String: s, Valid: len(s) > 0}
+	}
+
+	p.zone = str("zone")
+	p.account = str("account")
+
+	if s := c.QueryParam("ip_version"); len(s) > 0 {
+		switch s {
+		case "4", "v4":
+			p.ipVersion = ntpdb.NullServersIpVersion{ServersIpVersion: ntpdb.ServersIpVersionV4, Valid: true}
+		case "6", "v6":
+			p.ipVersion = ntpdb.NullServersIpVersion{ServersIpVersion: ntpdb.ServersIpVersionV6, Valid: true}
+		default:
+			return nil, invalid("ip_version")
+		}
+	}
+
+	if s := c.QueryParam("min_score"); len(s) > 0 {
+		score, err := strconv.ParseFloat(s, 64)
+		if err != nil || math.IsNaN(score) {
+			return nil, invalid("min_score")
+		}
+		p.minScore = sql.NullFloat64{Float64: score, Valid: true}
+	}
+
+	netspeed := func(name string) (sql.NullInt32, error) {
+		s := c.QueryParam(name)
+		if len(s) == 0 {
+			return sql.NullInt32{}, nil
+		}
+		n, err := strconv.ParseUint(s, 10, 31)
+		if err != nil {
+			return sql.NullInt32{}, invalid(name)
+		}
+		return sql.NullInt32{Int32: int32(n), Valid: true}, nil
+	}
+
+	var err error
+	if p.minNetspeed, err = netspeed("min_netspeed"); err != nil {
+		return nil, err
+	}
+	if p.maxNetspeed, err = netspeed("max_netspeed"); err != nil {
+		return nil, err
+	}
+
+	if s := c.QueryParam("hostname"); len(s) > 0 {
+		escaped := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+		p.hostname = sql.NullString{String: "%" + escaped + "%", Valid: true}
+	}
+
+	// only the servers in the server list are public
+	if s := c.QueryParam("in_server_list"); len(s) > 0 {
+		inList, err := strconv.ParseBool(s)
+		if err != nil || !inList {
+			return nil, invalid("in_server_list")
+		}
+	}
+
+	if s := c.QueryParam("cursor"); len(s) > 0 {
+		sort, value, id, ok := decodeServerCursor(s)
+		if !ok || sort != p.sort {
+			return nil, invalid("cursor")
+		}
+		p.afterValue = &value
+		p.afterID = id
+	}
+
+	return p, nil
+}
+
+// encodeServerCursor returns the cursor for the page after the
+// server with the ID and sort value
+func encodeServerCursor(sort, value string, id uint32) string {
+	s := sort + ":" + value + ":" + strconv.FormatUint(uint64(id), 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(s))
+}
+
+func decodeServerCursor(cursor string) (string, float64, uint32, bool) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return "", 0, 0, false
+	}
+	parts := strings.SplitN(string(b), ":", 3)
+	if len(parts) != 3 {
+		return "", 0, 0, false
+	}
+	value, err := strconv.ParseFloat(parts[1], 64)
+	if err != nil || math.IsNaN(value) {
+		return "", 0, 0, false
+	}
+	id, err := strconv.ParseUint(parts[2], 10, 32)
+	if err != nil {
+		return "", 0, 0, false
+	}
+	return parts[0], value, uint32(id), true
+}
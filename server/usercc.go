@@ -0,0 +1,129 @@
+package server
+
+import (
+	"net/http"
+	"net/url"
+	"sort"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"golang.org/x/sync/errgroup"
+
+	"go.ntppool.org/common/logger"
+	"go.ntppool.org/common/tracing"
+	chdb "go.ntppool.org/data-api/chdb"
+	"go.ntppool.org/data-api/ntpdb"
+)
+
+// userCountryDay is the DNS queries by country and the netspeed share
+// of the zones on one day
+type userCountryDay struct {
+	Date      string
+	Count4    uint64
+	Count6    uint64
+	UserCC    []chdb.UserCountryCount
+	ZoneStats ntpdb.ZoneStats
+}
+
+// userCountryHistory returns the DNS queries by user country and the
+// zone netspeed share for each day from 'from' to 'to'.
+func (srv *Server) userCountryHistory(c echo.Context) error {
+	log := logger.Setup()
+	ctx, span := tracing.Tracer().Start(c.Request().Context(), "userCountryHistory")
+	defer span.End()
+
+	// for errors, a shorter cache time
+	c.Response().Header().Set("Cache-Control", "public,max-age=300")
+
+	// only the time range parameters are used
+	query := url.Values{}
+	for _, k := range []string{"from", "to"} {
+		if v := c.QueryParam(k); len(v) > 0 {
+			query.Set(k, v)
+		}
+	}
+	if c.QueryString() != query.Encode() {
+		// better URLs are forever
+		u := "https://www.ntppool.org/api/data/usercc/history"
+		if len(query) > 0 {
+			u += "?" + query.Encode()
+		}
+		c.Response().Header().Set("Cache-Control", "public,max-age=10400")
+		return c.Redirect(http.StatusPermanentRedirect, u)
+	}
+
+	from, to, err := parseDateRange(c, 30, 400)
+	if err != nil {
+		return err
+	}
+
+	queryGroup, ctx := errgroup.WithContext(ctx)
+
+	var userCountry []chdb.UserCountryDay
+
+	queryGroup.Go(func() error {
+		var err error
+		userCountry, err = srv.ch.UserCountrySeries(ctx, from, to)
+		if err != nil {
+			log.ErrorContext(ctx, "UserCountrySeries", "err", err)
+		}
+		return err
+	})
+
+	var zoneStats []ntpdb.ZoneStatsDay
+
+	queryGroup.Go(func() error {
+		var err error
+		zoneStats, err = ntpdb.GetZoneStatsSeries(ctx, srv.db, from, to)
+		if err != nil {
+			log.ErrorContext(ctx, "GetZoneStatsSeries", "err", err)
+		}
+		return err
+	})
+
+	err = queryGroup.Wait()
+	if err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+
+	days := map[string]*userCountryDay{}
+	day := func(date string) *userCountryDay {
+		if _, ok := days[date]; !ok {
+			days[date] = &userCountryDay{
+				Date:      date,
+				UserCC:    []chdb.UserCountryCount{},
+				ZoneStats: ntpdb.ZoneStats{},
+			}
+		}
+		return days[date]
+	}
+
+	for _, uc := range userCountry {
+		d := day(uc.Date)
+		d.Count4 = uc.Count4
+		d.Count6 = uc.Count6
+		d.UserCC = uc.UserCC
+	}
+	for _, zs := range zoneStats {
+		day(zs.Date).ZoneStats = zs.ZoneStats
+	}
+
+	r := struct {
+		From string
+		To   string
+		Days []*userCountryDay
+	}{
+		From: from.Format(time.DateOnly),
+		To:   to.Format(time.DateOnly),
+		Days: []*userCountryDay{},
+	}
+
+	for _, d := range days {
+		r.Days = append(r.Days, d)
+	}
+	sort.Slice(r.Days, func(i, j int) bool { return r.Days[i].Date < r.Days[j].Date })
+
+	c.Response().Header().Set("Cache-Control", "public,max-age=3600")
+
+	return c.JSON(http.StatusOK, r)
+}
@@ -0,0 +1,101 @@
+package server
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/otel/attribute"
+
+	"go.ntppool.org/common/logger"
+	"go.ntppool.org/common/tracing"
+	chdb "go.ntppool.org/data-api/chdb"
+	"go.ntppool.org/data-api/ntpdb"
+)
+
+// vendorZoneQueries returns the DNS queries by day for an approved
+// vendor zone (like example.pool.ntp.org), by query type and user
+// country.
+func (srv *Server) vendorZoneQueries(c echo.Context) error {
+	log := logger.Setup()
+	ctx, span := tracing.Tracer().Start(c.Request().Context(), "vendorzone.queries")
+	defer span.End()
+
+	// for errors and 404s, a shorter cache time
+	c.Response().Header().Set("Cache-Control", "public,max-age=300")
+
+	zoneParam := c.Param("zone")
+	log = log.With("zone_param", zoneParam)
+	span.SetAttributes(attribute.String("zone_param", zoneParam))
+
+	name := strings.TrimSuffix(strings.ToLower(zoneParam), ".")
+	label, origin, ok := strings.Cut(name, ".")
+	if !ok || len(label) == 0 || len(origin) == 0 {
+		return c.String(http.StatusNotFound, "Not found")
+	}
+
+	// only the time range parameters are used
+	query := url.Values{}
+	for _, k := range []string{"from", "to"} {
+		if v := c.QueryParam(k); len(v) > 0 {
+			query.Set(k, v)
+		}
+	}
+
+	if name != zoneParam || c.QueryString() != query.Encode() {
+		// better URLs are forever
+		u := "https://www.ntppool.org/api/data/dns/vendor/" + name
+		if len(query) > 0 {
+			u += "?" + query.Encode()
+		}
+		c.Response().Header().Set("Cache-Control", "public,max-age=10400")
+		return c.Redirect(http.StatusPermanentRedirect, u)
+	}
+
+	from, to, err := parseDateRange(c, 30, 90)
+	if err != nil {
+		return err
+	}
+
+	vz, err := srv.db.GetVendorZone(ctx, ntpdb.GetVendorZoneParams{
+		ZoneName: label,
+		Origin:   origin,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.String(http.StatusNotFound, "Not found")
+		}
+		log.ErrorContext(ctx, "GetVendorZone", "err", err)
+		return c.String(http.StatusInternalServerError, "database error")
+	}
+
+	series, err := srv.ch.VendorZoneQueries(ctx, vz.Origin, vz.ZoneName, from, to)
+	if err != nil {
+		log.ErrorContext(ctx, "VendorZoneQueries", "err", err)
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+
+	r := struct {
+		Zone       string
+		Origin     string
+		ClientType ntpdb.VendorZonesClientType
+		From       string
+		To         string
+		Series     []chdb.VendorZoneDay
+	}{
+		Zone:       vz.ZoneName,
+		Origin:     vz.Origin,
+		ClientType: vz.ClientType,
+		From:       from.Format(time.DateOnly),
+		To:         to.Format(time.DateOnly),
+		Series:     series,
+	}
+
+	c.Response().Header().Set("Cache-Control", "public,max-age=3600")
+
+	return c.JSONPretty(http.StatusOK, r, "")
+}
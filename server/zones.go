@@ -10,7 +10,6 @@ import (
 	"github.com/labstack/echo/v4"
 	"go.ntppool.org/common/logger"
 	"go.ntppool.org/common/tracing"
-	"go.ntppool.org/data-api/ntpdb"
 )
 
 func (srv *Server) zoneCounts(c echo.Context) error {
@@ -23,7 +22,7 @@ func (srv *Server) zoneCounts(c echo.Context) error {
 	c.Response().Header().Set("Access-Control-Allow-Origin", "*")
 	c.Response().Header().Del("Vary")
 
-	q := ntpdb.NewWrappedQuerier(ntpdb.New(srv.db))
+	q := srv.db
 
 	zone, err := q.GetZoneByName(ctx, c.Param("zone_name"))
 	if err != nil || zone.ID == 0 {